@@ -1,19 +1,32 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/lazeratops/optimusdime/src/converter"
+	"github.com/lazeratops/optimusdime/src/converter/cache"
 	"github.com/lazeratops/optimusdime/src/converter/currencylayer"
+	"github.com/lazeratops/optimusdime/src/converter/ecb"
 	"github.com/lazeratops/optimusdime/src/converter/exchangeapi"
+	"github.com/lazeratops/optimusdime/src/converter/riksbank"
 	"github.com/lazeratops/optimusdime/src/document"
 	"github.com/lazeratops/optimusdime/src/importer"
 	"github.com/lazeratops/optimusdime/src/llm"
 	"github.com/lazeratops/optimusdime/src/parser"
+	"github.com/lazeratops/optimusdime/src/profile"
+	"github.com/lazeratops/optimusdime/src/util"
 )
 
 const resultsBanner = `
@@ -23,79 +36,192 @@ const resultsBanner = `
 `
 
 func main() {
-	csvPath := flag.String("statement", "", "Path to CSV file of bank statement")
+	if len(os.Args) > 1 && os.Args[1] == "profiles" {
+		if err := runProfiles(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "rates" {
+		if err := runRates(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	csvPath := flag.String("statement", "", "Path to bank statement (CSV, XLSX, OFX/QFX, camt.05x XML or MT940)")
 	openaiApiKey := flag.String("oai_key", "", "OpenAI API Key")
 	targetCurrency := flag.String("target_currenct", "SEK", "Target currency")
 	currencyLayerApiKey := flag.String("currencylayer_key", "", "CurrencyLayer API Key")
+	currencyLayerPlan := flag.String("currencylayer_plan", "free", "CurrencyLayer plan of the key: free (plain HTTP), basic (HTTPS) or professional (HTTPS, a statement's rates in one timeframe request per year)")
+	providerOrder := flag.String("providers", "exchangeapi,currencylayer", "Comma-separated rate providers to try in order: exchangeapi, currencylayer (skipped without -currencylayer_key), ecb or riksbank")
+	parallelism := flag.Int("parallelism", converter.DefaultParallelism, "How many exchange rates to look up at once")
+	rateLimit := flag.String("rate_limit", "", "Rate API requests per second, for all providers (e.g. 5) or per provider (e.g. exchangeapi=10,riksbank=2)")
+	httpTimeout := flag.Duration("http_timeout", 30*time.Second, "Timeout of each exchange rate or OpenAI API request")
+	rateFallback := flag.String("rate_fallback", "previous", "Rate to use on dates without one, such as weekends: previous, next or nearest date with a rate, or fail")
+	rateFallbackDays := flag.Int("rate_fallback_days", converter.DefaultFallbackDays, "Furthest a -rate_fallback may look, in days")
+	exchangeApiMirrors := flag.String("exchangeapi_mirrors", "", "Comma-separated exchangeapi URLs to try in order, with {currency}, {date} and {version} placeholders (default: the Cloudflare and jsDelivr mirrors)")
+	exchangeApiVersion := flag.String("exchangeapi_version", "date", "exchangeapi release to read rates from: date (each transaction date's), latest or a release date YYYY-MM-DD")
+	riksbankUrl := flag.String("riksbank_url", "", "Riksbank SWEA API base URL (default: https://api.riksbank.se/swea/v1)")
+	ecbSource := flag.String("ecb_source", "", "ECB eurofxref history file (XML, CSV or zip) as a path or URL (default: "+ecb.DefaultSource+")")
+	detector := flag.String("detector", "auto", "Column detection: auto (heuristic, OpenAI when unsure), heuristic or llm")
+	sheet := flag.String("sheet", "", "Sheet to import from an XLSX statement (default: auto-detect)")
+	dateFormat := flag.String("date_format", "", "Date format of CSV/XLSX statements, e.g. DD/MM/YYYY or a Go layout (default: inferred from the date column)")
+	roundingMode := flag.String("rounding", "half-even", "Rounding of amounts to the currency's minor units: half-even or half-up")
+	statementCurrency := flag.String("currency", "", "Statement currency for rows without one (default: from the profile, headers or file metadata)")
+	outputFormat := flag.String("format", "csv", "Output format of converted and failed transactions: csv or json")
+
+	profilesFile := flag.String("profiles_file", "", "Column mapping profile store (default: profiles.json in the user config dir)")
+	noProfiles := flag.Bool("no_profiles", false, "Do not read or save column mapping profiles")
+
+	rateCacheFile := flag.String("rate_cache", "", "Exchange rate cache (default: rates.json in the user cache dir)")
+	noRateCache := flag.Bool("no_rate_cache", false, "Do not read or save cached exchange rates")
+	offline := flag.Bool("offline", false, "Only use cached exchange rates")
 
 	flag.Parse()
 
+	// Stop cleanly on Ctrl-C rather than leaving requests hanging.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	if *csvPath == "" {
 		log.Fatal("Please provide a file path using -statement flag")
 	}
-	if *openaiApiKey == "" {
-		log.Fatal("Please provide an OpenAI API key using the -oai_key flag")
+
+	rounding, err := document.ParseRoundingMode(*roundingMode)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fallback, err := converter.ParseFallbackPolicy(*rateFallback)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rateLimits, err := parseRateLimits(*rateLimit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *outputFormat != "csv" && *outputFormat != "json" {
+		log.Fatalf("unknown -format %q (want csv or json)", *outputFormat)
 	}
 
-	var clApi *currencylayer.Api
-	var err error
-	if currencyLayerApiKey != nil {
-		clApi, err = currencylayer.NewCurrencyLayer("", *currencyLayerApiKey)
+	// Check currencies up front so a typo fails before any API call.
+	tc, err := document.Currency(*targetCurrency).Normalize()
+	if err != nil {
+		log.Fatalf("invalid -target_currenct: %v", err)
+	}
+	var currency document.Currency
+	if *statementCurrency != "" {
+		currency, err = document.Currency(*statementCurrency).Normalize()
 		if err != nil {
-			log.Fatal(err)
+			log.Fatalf("invalid -currency: %v", err)
 		}
 	}
 
-	llm, err := llm.NewOpenAi(llm.Config{
-		ApiKey: *openaiApiKey,
-	})
-	if err != nil {
-		log.Fatal(err)
+	var dateLayout string
+	if *dateFormat != "" {
+		layout, err := util.DateLayout(*dateFormat)
+		if err != nil {
+			log.Fatal(err)
+		}
+		dateLayout = layout
 	}
 
-	parser := parser.NewParser(llm)
-	importer := importer.NewCsv(parser)
+	var profiles *profile.Store
+	if !*noProfiles {
+		store, err := openProfiles(*profilesFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		profiles = store
+	}
 
-	doc, err := importer.Import(*csvPath, nil)
-	if err != nil {
-		log.Fatal(err)
+	if *offline && *noRateCache {
+		log.Fatal("-offline needs the rate cache, drop -no_rate_cache")
 	}
-	converterApi, err := exchangeapi.NewExchangeApi("")
+	var rateCache *cache.Store
+	if !*noRateCache {
+		rateCache, err = openRateCache(*rateCacheFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	providers, err := newRateProviders(rateProviderConfig{
+		Order:               *providerOrder,
+		CurrencyLayerApiKey: *currencyLayerApiKey,
+		CurrencyLayerPlan:   *currencyLayerPlan,
+		ExchangeApiMirrors:  *exchangeApiMirrors,
+		ExchangeApiVersion:  *exchangeApiVersion,
+		EcbSource:           *ecbSource,
+		RiksbankUrl:         *riksbankUrl,
+		RateLimits:          rateLimits,
+		HttpClient:          &http.Client{Timeout: *httpTimeout},
+		Cache:               rateCache,
+		Offline:             *offline,
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
+	rateConverter := converter.NewRateConverter(converter.NewChain(providers...))
+	rateConverter.SetRounding(rounding)
+	rateConverter.SetFallback(fallback, *rateFallbackDays)
+	rateConverter.SetParallelism(*parallelism)
 
-	tc := document.Currency(*targetCurrency)
-	convertedDoc, failedDoc, err := converterApi.Convert(tc, doc)
+	doc, report, err := importStatement(ctx, *csvPath, *openaiApiKey, &http.Client{Timeout: *httpTimeout}, *detector, *sheet, currency, dateLayout, rounding, profiles)
+	if errors.Is(err, parser.ErrAmbiguousDates) {
+		log.Fatalf("%v; pass -date_format, e.g. DD/MM/YYYY", err)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
+	total := len(doc.Transactions)
+	doc, unknownDoc := splitUnknownCurrencies(doc)
 
-	if failedDoc.Transactions != nil && clApi != nil {
-		convertedDoc2, failedConvertedDoc2, err := clApi.Convert(tc, failedDoc)
+	convertedDoc, failedDoc := &document.Document{}, &document.Document{}
+	if len(doc.Transactions) > 0 {
+		convertedDoc, failedDoc, err = rateConverter.Convert(ctx, tc, doc)
+		// Save the rates fetched so far even when the conversion failed.
+		if rateCache != nil {
+			if err := rateCache.Flush(); err != nil {
+				log.Printf("\nfailed to save rate cache: %v", err)
+			}
+		}
 		if err != nil {
 			log.Fatal(err)
 		}
-		convertedDoc.Transactions = append(convertedDoc.Transactions, convertedDoc2.Transactions...)
-		failedDoc.Transactions = append(failedDoc.Transactions, failedConvertedDoc2.Transactions...)
 	}
+	failedDoc.Transactions = append(failedDoc.Transactions, unknownDoc.Transactions...)
 
 	fileName := filepath.Base(*csvPath)
+	baseName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
 
 	successFilename := fmt.Sprintf("convered_%s", fileName)
 	failedFilename := fmt.Sprintf("failed_%s", fileName)
-	err = convertedDoc.SaveToCSV(successFilename)
+	reportFilename := fmt.Sprintf("report_%s.csv", baseName)
+	if *outputFormat == "json" {
+		successFilename = fmt.Sprintf("convered_%s.json", baseName)
+		failedFilename = fmt.Sprintf("failed_%s.json", baseName)
+	}
+	err = saveDocument(convertedDoc, successFilename, *outputFormat)
 	if err != nil {
 		log.Fatal(err)
 	}
-	err = failedDoc.SaveToCSV(failedFilename)
+	err = saveDocument(failedDoc, failedFilename, *outputFormat)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if report != nil {
+		err = report.SaveToCSV(reportFilename)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 	println(resultsBanner)
-	println(fmt.Sprintf("Target Currency: %s", *targetCurrency))
+	println(fmt.Sprintf("Target Currency: %s", tc))
 	println(fmt.Sprintf("- %s", successFilename))
 	println(fmt.Sprintf("- %s", failedFilename))
+	if report != nil {
+		println(fmt.Sprintf("- %s", reportFilename))
+	}
 	println()
 	lSuccess := len(convertedDoc.Transactions)
 	lFail := len(failedDoc.Transactions)
@@ -104,9 +230,256 @@ func main() {
 	t.SetOutputMirror(os.Stdout)
 	t.AppendHeader(table.Row{"Total Transactions", "Total Processed", "Succeeded #", "Failed #"})
 	t.AppendRows([]table.Row{
-		{len(doc.Transactions), lSuccess + lFail, lSuccess, lFail},
+		{total, lSuccess + lFail, lSuccess, lFail},
 	})
 	t.AppendSeparator()
 	t.SetStyle(table.StyleBold)
 	t.Render()
+
+	if lSuccess > 0 {
+		byProvider := make(map[string]int)
+		for _, t := range convertedDoc.Transactions {
+			byProvider[t.Conversion.RateProvider]++
+		}
+		pt := table.NewWriter()
+		pt.SetOutputMirror(os.Stdout)
+		pt.AppendHeader(table.Row{"Provider", "Converted #"})
+		for _, p := range providers {
+			if n := byProvider[p.Name()]; n > 0 {
+				pt.AppendRow(table.Row{p.Name(), n})
+			}
+		}
+		pt.SetStyle(table.StyleBold)
+		pt.Render()
+	}
+
+	if report != nil {
+		rt := table.NewWriter()
+		rt.SetOutputMirror(os.Stdout)
+		rt.AppendHeader(table.Row{"Rows", "Imported", "Header", "Blank", "Summary", "Footer", "Rejected"})
+		rt.AppendRow(table.Row{
+			report.Rows,
+			report.Transactions,
+			report.Count(parser.RowPreamble) + report.Count(parser.RowHeader),
+			report.Count(parser.RowBlank),
+			report.Count(parser.RowSummary),
+			report.Count(parser.RowFooter),
+			report.Count(parser.RowRejected),
+		})
+		rt.SetStyle(table.StyleBold)
+		rt.Render()
+
+	}
+}
+
+type rateProviderConfig struct {
+	// Order is a comma-separated list of provider names.
+	Order               string
+	CurrencyLayerApiKey string
+	// CurrencyLayerPlan is parsed by currencylayer.ParsePlan.
+	CurrencyLayerPlan string
+	// ExchangeApiMirrors is a comma-separated list of URL templates.
+	ExchangeApiMirrors string
+	// ExchangeApiVersion is parsed by exchangeapi.ParseVersion.
+	ExchangeApiVersion string
+	EcbSource          string
+	RiksbankUrl        string
+	// RateLimits are API requests per second by provider name. The "" entry
+	// applies to providers without one.
+	RateLimits map[string]float64
+	HttpClient *http.Client
+	Cache      *cache.Store
+	Offline    bool
+}
+
+// parseRateLimits reads "5" or "exchangeapi=10,riksbank=2".
+func parseRateLimits(s string) (map[string]float64, error) {
+	limits := make(map[string]float64)
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			name, value = "", part
+		}
+		perSecond, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || perSecond <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q: want a positive number of lookups per second", part)
+		}
+		limits[strings.ToLower(strings.TrimSpace(name))] = perSecond
+	}
+	return limits, nil
+}
+
+// newRateProviders builds the providers named in cfg.Order. currencylayer
+// needs an API key and is left out without one. With a rate cache, each
+// provider answers from it first; offline, only from it.
+func newRateProviders(cfg rateProviderConfig) ([]converter.RateProvider, error) {
+	var providers []converter.RateProvider
+	for _, name := range strings.Split(cfg.Order, ",") {
+		var provider converter.RateProvider
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "":
+			continue
+		case "exchangeapi":
+			version, err := exchangeapi.ParseVersion(cfg.ExchangeApiVersion)
+			if err != nil {
+				return nil, err
+			}
+			api, err := exchangeapi.NewExchangeApi("")
+			if err != nil {
+				return nil, err
+			}
+			if cfg.ExchangeApiMirrors != "" {
+				if err := api.SetMirrors(strings.Split(cfg.ExchangeApiMirrors, ",")...); err != nil {
+					return nil, err
+				}
+			}
+			api.SetVersion(version)
+			provider = api
+		case "currencylayer":
+			if cfg.CurrencyLayerApiKey == "" {
+				log.Printf("\nskipping currencylayer: no -currencylayer_key given")
+				continue
+			}
+			plan, err := currencylayer.ParsePlan(cfg.CurrencyLayerPlan)
+			if err != nil {
+				return nil, err
+			}
+			api, err := currencylayer.NewCurrencyLayer("", cfg.CurrencyLayerApiKey)
+			if err != nil {
+				return nil, err
+			}
+			api.SetPlan(plan)
+			provider = api
+		case "ecb":
+			api, err := ecb.NewEcb(cfg.EcbSource)
+			if err != nil {
+				return nil, err
+			}
+			provider = api
+		case "riksbank":
+			api, err := riksbank.NewRiksbank(cfg.RiksbankUrl)
+			if err != nil {
+				return nil, err
+			}
+			provider = api
+		default:
+			return nil, fmt.Errorf("unknown rate provider %q (want exchangeapi, currencylayer, ecb or riksbank)", name)
+		}
+		client := cfg.HttpClient
+		perSecond, ok := cfg.RateLimits[name]
+		if !ok {
+			perSecond = cfg.RateLimits[""]
+		}
+		// The limit applies to the provider's requests, so rates it or the
+		// cache already has cost nothing.
+		if perSecond > 0 {
+			client = converter.NewRateLimitedClient(client, perSecond)
+		}
+		if s, ok := provider.(interface{ SetHttpClient(*http.Client) }); ok && client != nil {
+			s.SetHttpClient(client)
+		}
+		if cfg.Cache != nil {
+			cached := cache.NewProvider(cfg.Cache, provider)
+			cached.SetOffline(cfg.Offline)
+			provider = cached
+		}
+		providers = append(providers, provider)
+	}
+	if len(providers) == 0 {
+		return nil, errors.New("no rate providers configured, check -providers")
+	}
+	return providers, nil
+}
+
+func saveDocument(doc *document.Document, filename string, format string) error {
+	if format == "json" {
+		return doc.SaveToJSON(filename)
+	}
+	return doc.SaveToCSV(filename)
+}
+
+// splitUnknownCurrencies normalizes transaction currencies and sets aside
+// transactions whose currency is not in ISO 4217, as no rate API can
+// convert them.
+func splitUnknownCurrencies(doc *document.Document) (*document.Document, *document.Document) {
+	known, unknown := &document.Document{}, &document.Document{}
+	for _, t := range doc.Transactions {
+		c, err := t.Currency.Normalize()
+		if err != nil {
+			log.Printf("\nnot converting %q on %s: %v", t.Description, t.Date.Format("2006-01-02"), err)
+			unknown.Transactions = append(unknown.Transactions, t)
+			continue
+		}
+		t.Currency = c
+		known.Transactions = append(known.Transactions, t)
+	}
+	return known, unknown
+}
+
+// importStatement reads a statement of any supported format. The report is
+// only set for tabular statements, where rows can be skipped.
+func importStatement(ctx context.Context, path string, openaiApiKey string, client *http.Client, detectorName string, sheet string, currency document.Currency, dateLayout string, rounding document.RoundingMode, profiles *profile.Store) (*document.Document, *parser.Report, error) {
+	var doc *document.Document
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ofx", ".qfx":
+		doc, err = importer.NewOfx().Import(path, &importer.OfxConfig{
+			DefaultCurrency: currency,
+		})
+		return doc, nil, err
+	case ".xml", ".camt", ".053", ".052":
+		doc, err = importer.NewCamt().Import(path, nil)
+		return doc, nil, err
+	case ".sta", ".mt940", ".940":
+		doc, err = importer.NewMt940().Import(path)
+		return doc, nil, err
+	}
+
+	detector, err := newDetector(detectorName, openaiApiKey, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parser := parser.NewParser(detector, &parser.Config{
+		Profiles:        profiles,
+		DefaultCurrency: currency,
+		DateLayout:      dateLayout,
+		Rounding:        rounding,
+	})
+	if strings.EqualFold(filepath.Ext(path), ".xlsx") {
+		return importer.NewXlsx(parser).Import(ctx, path, &importer.XlsxConfig{
+			Sheet: sheet,
+		})
+	}
+	return importer.NewCsv(parser).Import(ctx, path, nil)
+}
+
+func newDetector(name string, openaiApiKey string, client *http.Client) (llm.Llm, error) {
+	var openAi llm.Llm
+	if openaiApiKey != "" {
+		oai, err := llm.NewOpenAi(llm.Config{
+			ApiKey:     openaiApiKey,
+			HttpClient: client,
+		})
+		if err != nil {
+			return nil, err
+		}
+		openAi = oai
+	}
+
+	switch name {
+	case "auto":
+		return llm.NewHeuristic(openAi, 0), nil
+	case "heuristic":
+		return llm.NewHeuristic(nil, 0), nil
+	case "llm":
+		if openAi == nil {
+			return nil, errors.New("please provide an OpenAI API key using the -oai_key flag")
+		}
+		return openAi, nil
+	}
+	return nil, fmt.Errorf("unknown detector %q", name)
 }
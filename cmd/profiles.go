@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/lazeratops/optimusdime/src/llm"
+	"github.com/lazeratops/optimusdime/src/parser"
+	"github.com/lazeratops/optimusdime/src/profile"
+)
+
+const profilesUsage = `usage: optimusdime profiles <command> [flags]
+
+commands:
+  list                                 list stored column mapping profiles
+  show <fingerprint>                   show one profile
+  edit <fingerprint> [-name N] [-currency C] [-set element=index ...]
+                                       rename a profile or change its columns
+                                       and statement currency
+  delete <fingerprint>                 delete a profile
+
+Fingerprints may be shortened to any unique prefix.`
+
+type elementIndices map[string]int
+
+func (e elementIndices) String() string {
+	return fmt.Sprint(map[string]int(e))
+}
+
+func (e elementIndices) Set(v string) error {
+	name, idx, ok := strings.Cut(v, "=")
+	if !ok {
+		return fmt.Errorf("expected element=index, got %q", v)
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	if elements := parser.ElementNames(); !slices.Contains(elements, name) {
+		return fmt.Errorf("unknown element %q (want one of %s)", name, strings.Join(elements, ", "))
+	}
+	i, err := strconv.Atoi(idx)
+	if err != nil {
+		return fmt.Errorf("invalid index %q: %w", idx, err)
+	}
+	e[name] = i
+	return nil
+}
+
+func openProfiles(path string) (*profile.Store, error) {
+	if path == "" {
+		p, err := profile.DefaultPath()
+		if err != nil {
+			return nil, err
+		}
+		path = p
+	}
+	return profile.NewStore(path), nil
+}
+
+func runProfiles(args []string) error {
+	if len(args) == 0 {
+		return errors.New(profilesUsage)
+	}
+	command, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("profiles "+command, flag.ExitOnError)
+	file := fs.String("file", "", "Column mapping profile store (default: profiles.json in the user config dir)")
+	name := fs.String("name", "", "New profile name (edit only)")
+	currency := fs.String("currency", "", "Statement currency for layouts without a currency column (edit only)")
+	set := elementIndices{}
+	fs.Var(set, "set", "Set a column, e.g. -set amount=3 (edit only, repeatable)")
+
+	// Allow the fingerprint before or after the flags.
+	var fingerprint string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		fingerprint, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fingerprint == "" && fs.NArg() > 0 {
+		fingerprint = fs.Arg(0)
+	}
+
+	store, err := openProfiles(*file)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "list":
+		return listProfiles(store)
+	case "show", "edit", "delete":
+		if fingerprint == "" {
+			return fmt.Errorf("profiles %s needs a fingerprint\n\n%s", command, profilesUsage)
+		}
+	default:
+		return fmt.Errorf("unknown profiles command %q\n\n%s", command, profilesUsage)
+	}
+
+	p, err := store.Get(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "show":
+		showProfile(p)
+	case "edit":
+		if *name == "" && *currency == "" && len(set) == 0 {
+			return errors.New("nothing to edit: use -name, -currency and/or -set")
+		}
+		if *name != "" {
+			p.Name = *name
+		}
+		if *currency != "" {
+			c, err := document.Currency(*currency).Normalize()
+			if err != nil {
+				return err
+			}
+			p.Currency = c
+		}
+		if p.Indices == nil {
+			p.Indices = make(map[string]int)
+		}
+		for element, i := range set {
+			if i < llm.NotFound || i >= len(p.Header) {
+				return fmt.Errorf("column %d is out of range for a %d column header (use %d for none)", i, len(p.Header), llm.NotFound)
+			}
+			p.Indices[element] = i
+		}
+		if err := store.Put(*p); err != nil {
+			return err
+		}
+		showProfile(p)
+	case "delete":
+		if err := store.Delete(p.Fingerprint); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted profile %s\n", p.Fingerprint)
+	}
+	return nil
+}
+
+func listProfiles(store *profile.Store) error {
+	profiles, err := store.List()
+	if err != nil {
+		return err
+	}
+	if len(profiles) == 0 {
+		fmt.Printf("No profiles in %s\n", store.Path())
+		return nil
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Fingerprint", "Name", "Columns", "Uses", "Last Used"})
+	for _, p := range profiles {
+		lastUsed := "never"
+		if !p.LastUsedAt.IsZero() {
+			lastUsed = p.LastUsedAt.Format("2006-01-02")
+		}
+		t.AppendRow(table.Row{p.Fingerprint, p.Name, len(p.Header), p.UseCount, lastUsed})
+	}
+	t.SetStyle(table.StyleBold)
+	t.Render()
+	return nil
+}
+
+func showProfile(p *profile.Profile) {
+	fmt.Printf("Fingerprint: %s\n", p.Fingerprint)
+	fmt.Printf("Name:        %s\n", p.Name)
+	fmt.Printf("Currency:    %s\n", p.Currency)
+	fmt.Printf("Created:     %s\n", p.CreatedAt.Format("2006-01-02 15:04"))
+	fmt.Printf("Uses:        %d\n", p.UseCount)
+
+	elements := make([]string, 0, len(p.Indices))
+	for element := range p.Indices {
+		elements = append(elements, element)
+	}
+	sort.Strings(elements)
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Element", "Index", "Header"})
+	for _, element := range elements {
+		i := p.Indices[element]
+		var header string
+		if i >= 0 && i < len(p.Header) {
+			header = p.Header[i]
+		}
+		t.AppendRow(table.Row{element, i, header})
+	}
+	t.SetStyle(table.StyleBold)
+	t.Render()
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/lazeratops/optimusdime/src/converter"
+	"github.com/lazeratops/optimusdime/src/converter/cache"
+	"github.com/lazeratops/optimusdime/src/document"
+)
+
+const ratesUsage = `usage: optimusdime rates <command> [flags]
+
+commands:
+  list   [-provider P] [-base C] [-quote C] [-before YYYY-MM-DD]
+                                       list cached exchange rates
+  prune  [-provider P] [-base C] [-quote C] [-before YYYY-MM-DD] [-all]
+                                       delete cached rates
+  warm   -from YYYY-MM-DD [-to YYYY-MM-DD] -currencies C,C... [-target C]
+         [-providers P,P...] [-currencylayer_key K]
+         [-currencylayer_plan P] [-exchangeapi_mirrors U,U...]
+         [-exchangeapi_version V] [-ecb_source S] [-riksbank_url U]
+                                       fetch and cache rates ahead of a run
+  export [-format csv|json] [-out FILE] [filters as for list]
+                                       write cached rates to a file or stdout
+
+Rates for today or later are never cached.`
+
+func openRateCache(path string) (*cache.Store, error) {
+	if path == "" {
+		p, err := cache.DefaultPath()
+		if err != nil {
+			return nil, err
+		}
+		path = p
+	}
+	return cache.NewStore(path), nil
+}
+
+func runRates(args []string) error {
+	if len(args) == 0 {
+		return errors.New(ratesUsage)
+	}
+	command, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("rates "+command, flag.ExitOnError)
+	file := fs.String("file", "", "Rate cache (default: rates.json in the user cache dir)")
+	provider := fs.String("provider", "", "Only rates from this provider")
+	base := fs.String("base", "", "Only rates from this currency")
+	quote := fs.String("quote", "", "Only rates to this currency")
+	before := fs.String("before", "", "Only rates for dates before YYYY-MM-DD")
+	all := fs.Bool("all", false, "Delete every cached rate (prune only)")
+	from := fs.String("from", "", "First date to fetch, YYYY-MM-DD (warm only)")
+	to := fs.String("to", "", "Last date to fetch, YYYY-MM-DD (warm only, default: yesterday)")
+	currencies := fs.String("currencies", "", "Comma-separated currencies to fetch rates for (warm only)")
+	target := fs.String("target", "SEK", "Currency to fetch rates to (warm only)")
+	providerOrder := fs.String("providers", "exchangeapi,currencylayer", "Rate providers to try in order (warm only)")
+	currencyLayerApiKey := fs.String("currencylayer_key", "", "CurrencyLayer API Key (warm only)")
+	exchangeApiMirrors := fs.String("exchangeapi_mirrors", "", "exchangeapi URLs to try in order (warm only)")
+	exchangeApiVersion := fs.String("exchangeapi_version", "date", "exchangeapi release: date, latest or YYYY-MM-DD (warm only)")
+	currencyLayerPlan := fs.String("currencylayer_plan", "free", "CurrencyLayer plan of the key: free, basic or professional (warm only)")
+	ecbSource := fs.String("ecb_source", "", "ECB eurofxref history file as a path or URL (warm only)")
+	riksbankUrl := fs.String("riksbank_url", "", "Riksbank SWEA API base URL (warm only)")
+	format := fs.String("format", "csv", "Export format: csv or json (export only)")
+	out := fs.String("out", "", "Export file (export only, default: stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	store, err := openRateCache(*file)
+	if err != nil {
+		return err
+	}
+
+	filter, err := rateFilter(*provider, *base, *quote, *before)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "list":
+		return listRates(store, filter)
+	case "prune":
+		if filter == (cache.Filter{}) && !*all {
+			return errors.New("prune needs a filter, or -all to delete every cached rate")
+		}
+		n, err := store.Prune(filter)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Deleted %d cached rates from %s\n", n, store.Path())
+		return nil
+	case "warm":
+		return warmRates(store, *from, *to, *currencies, *target, rateProviderConfig{
+			Order:               *providerOrder,
+			CurrencyLayerApiKey: *currencyLayerApiKey,
+			CurrencyLayerPlan:   *currencyLayerPlan,
+			ExchangeApiMirrors:  *exchangeApiMirrors,
+			ExchangeApiVersion:  *exchangeApiVersion,
+			EcbSource:           *ecbSource,
+			RiksbankUrl:         *riksbankUrl,
+			Cache:               store,
+		})
+	case "export":
+		return exportRates(store, filter, *format, *out)
+	}
+	return fmt.Errorf("unknown rates command %q\n\n%s", command, ratesUsage)
+}
+
+func rateFilter(provider string, base string, quote string, before string) (cache.Filter, error) {
+	filter := cache.Filter{
+		Provider: provider,
+	}
+	if base != "" {
+		c, err := document.Currency(base).Normalize()
+		if err != nil {
+			return cache.Filter{}, err
+		}
+		filter.Base = c
+	}
+	if quote != "" {
+		c, err := document.Currency(quote).Normalize()
+		if err != nil {
+			return cache.Filter{}, err
+		}
+		filter.Quote = c
+	}
+	if before != "" {
+		d, err := time.Parse("2006-01-02", before)
+		if err != nil {
+			return cache.Filter{}, fmt.Errorf("invalid -before: %w", err)
+		}
+		filter.Before = d
+	}
+	return filter, nil
+}
+
+func listRates(store *cache.Store, filter cache.Filter) error {
+	entries, err := store.List(filter)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Printf("No cached rates in %s\n", store.Path())
+		return nil
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Provider", "Base", "Quote", "Date", "Rate", "Rate Date", "Fetched"})
+	for _, e := range entries {
+		t.AppendRow(table.Row{e.Provider, e.Base, e.Quote, e.Date, e.Rate, e.RateDate.Format("2006-01-02"), e.FetchedAt.Format("2006-01-02 15:04")})
+	}
+	t.SetStyle(table.StyleBold)
+	t.Render()
+	return nil
+}
+
+// warmRates fetches the rates of every currency to target for each day of
+// the range, so that later runs, including offline ones, find them cached.
+func warmRates(store *cache.Store, from string, to string, currencies string, target string, cfg rateProviderConfig) error {
+	if from == "" || currencies == "" {
+		return errors.New("warm needs -from and -currencies")
+	}
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	end := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	if to != "" {
+		end, err = time.Parse("2006-01-02", to)
+		if err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+	}
+	tc, err := document.Currency(target).Normalize()
+	if err != nil {
+		return err
+	}
+	var sources []document.Currency
+	for _, c := range strings.Split(currencies, ",") {
+		sc, err := document.Currency(strings.TrimSpace(c)).Normalize()
+		if err != nil {
+			return err
+		}
+		sources = append(sources, sc)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	providers, err := newRateProviders(cfg)
+	if err != nil {
+		return err
+	}
+	// Save what was fetched even when interrupted.
+	defer func() {
+		if err := store.Flush(); err != nil {
+			fmt.Printf("Failed to save rate cache: %v\n", err)
+		}
+	}()
+	chain := converter.NewChain(providers...)
+	for _, sc := range sources {
+		if err := chain.Prefetch(ctx, sc, tc, start, end); err != nil {
+			fmt.Printf("Prefetching %s to %s failed, fetching by date: %v\n", sc, tc, err)
+		}
+	}
+
+	fetched, failed := 0, 0
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, sc := range sources {
+			if _, err := chain.Rate(ctx, sc, tc, date); err != nil {
+				if errors.Is(err, converter.ErrFatal) {
+					return err
+				}
+				fmt.Printf("%s %s to %s: %v\n", date.Format("2006-01-02"), sc, tc, err)
+				failed++
+				continue
+			}
+			fetched++
+		}
+	}
+	if err := store.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("Cached %d rates in %s (%d failed)\n", fetched, store.Path(), failed)
+	return nil
+}
+
+func exportRates(store *cache.Store, filter cache.Filter, format string, out string) error {
+	// Check the format before -out is created, so a typo does not empty
+	// an existing file.
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("unknown -format %q (want csv or json)", format)
+	}
+	entries, err := store.List(filter)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		return writeRates(os.Stdout, entries, format)
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if err := writeRates(file, entries, format); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+	return nil
+}
+
+func writeRates(w io.Writer, entries []cache.Entry, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case "csv":
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"Provider", "Base", "Quote", "Date", "Rate", "Rate Date", "Fetched At"}); err != nil {
+			return fmt.Errorf("failed to write headers: %w", err)
+		}
+		for _, e := range entries {
+			record := []string{
+				e.Provider,
+				string(e.Base),
+				string(e.Quote),
+				e.Date,
+				strconv.FormatFloat(e.Rate, 'f', -1, 64),
+				e.RateDate.Format("2006-01-02"),
+				e.FetchedAt.Format(time.RFC3339),
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("failed to write record: %w", err)
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	return fmt.Errorf("unknown -format %q (want csv or json)", format)
+}
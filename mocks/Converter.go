@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	document "github.com/lazeratops/optimusdime/src/document"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,34 +14,43 @@ type Converter struct {
 	mock.Mock
 }
 
-// Convert provides a mock function with given fields: targetCurrency, statement
-func (_m *Converter) Convert(targetCurrency document.Currency, statement *document.Document) (*document.Document, error) {
-	ret := _m.Called(targetCurrency, statement)
+// Convert provides a mock function with given fields: ctx, targetCurrency, statement
+func (_m *Converter) Convert(ctx context.Context, targetCurrency document.Currency, statement *document.Document) (*document.Document, *document.Document, error) {
+	ret := _m.Called(ctx, targetCurrency, statement)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Convert")
 	}
 
 	var r0 *document.Document
-	var r1 error
-	if rf, ok := ret.Get(0).(func(document.Currency, *document.Document) (*document.Document, error)); ok {
-		return rf(targetCurrency, statement)
+	var r1 *document.Document
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, document.Currency, *document.Document) (*document.Document, *document.Document, error)); ok {
+		return rf(ctx, targetCurrency, statement)
 	}
-	if rf, ok := ret.Get(0).(func(document.Currency, *document.Document) *document.Document); ok {
-		r0 = rf(targetCurrency, statement)
+	if rf, ok := ret.Get(0).(func(context.Context, document.Currency, *document.Document) *document.Document); ok {
+		r0 = rf(ctx, targetCurrency, statement)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*document.Document)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(document.Currency, *document.Document) error); ok {
-		r1 = rf(targetCurrency, statement)
+	if rf, ok := ret.Get(1).(func(context.Context, document.Currency, *document.Document) *document.Document); ok {
+		r1 = rf(ctx, targetCurrency, statement)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*document.Document)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, document.Currency, *document.Document) error); ok {
+		r2 = rf(ctx, targetCurrency, statement)
 	} else {
-		r1 = ret.Error(1)
+		r2 = ret.Error(2)
 	}
 
-	return r0, r1
+	return r0, r1, r2
 }
 
 // NewConverter creates a new instance of Converter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
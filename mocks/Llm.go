@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	llm "github.com/lazeratops/optimusdime/src/llm"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,9 +14,9 @@ type Llm struct {
 	mock.Mock
 }
 
-// FindElements provides a mock function with given fields: elements, content
-func (_m *Llm) FindElements(elements llm.DesiredElements, content string) (map[string]int, error) {
-	ret := _m.Called(elements, content)
+// FindElements provides a mock function with given fields: ctx, elements, content
+func (_m *Llm) FindElements(ctx context.Context, elements llm.DesiredElements, content string) (map[string]int, error) {
+	ret := _m.Called(ctx, elements, content)
 
 	if len(ret) == 0 {
 		panic("no return value specified for FindElements")
@@ -22,19 +24,19 @@ func (_m *Llm) FindElements(elements llm.DesiredElements, content string) (map[s
 
 	var r0 map[string]int
 	var r1 error
-	if rf, ok := ret.Get(0).(func(llm.DesiredElements, string) (map[string]int, error)); ok {
-		return rf(elements, content)
+	if rf, ok := ret.Get(0).(func(context.Context, llm.DesiredElements, string) (map[string]int, error)); ok {
+		return rf(ctx, elements, content)
 	}
-	if rf, ok := ret.Get(0).(func(llm.DesiredElements, string) map[string]int); ok {
-		r0 = rf(elements, content)
+	if rf, ok := ret.Get(0).(func(context.Context, llm.DesiredElements, string) map[string]int); ok {
+		r0 = rf(ctx, elements, content)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(map[string]int)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(llm.DesiredElements, string) error); ok {
-		r1 = rf(elements, content)
+	if rf, ok := ret.Get(1).(func(context.Context, llm.DesiredElements, string) error); ok {
+		r1 = rf(ctx, elements, content)
 	} else {
 		r1 = ret.Error(1)
 	}
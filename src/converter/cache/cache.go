@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/document"
+)
+
+// Entry is one cached rate. Date is the date that was asked for and
+// RateDate the date the provider's rate applies to.
+type Entry struct {
+	Provider  string            `json:"provider"`
+	Base      document.Currency `json:"base"`
+	Quote     document.Currency `json:"quote"`
+	Date      string            `json:"date"`
+	Rate      float64           `json:"rate"`
+	RateDate  time.Time         `json:"rate_date"`
+	FetchedAt time.Time         `json:"fetched_at"`
+}
+
+func (e Entry) key() string {
+	return Key(e.Provider, e.Base, e.Quote, e.Date)
+}
+
+// Key identifies a rate by provider, currency pair and date (YYYY-MM-DD).
+func Key(provider string, base document.Currency, quote document.Currency, date string) string {
+	return strings.Join([]string{provider, string(base), string(quote), date}, "|")
+}
+
+// Filter selects entries. Empty fields match everything.
+type Filter struct {
+	Provider string
+	Base     document.Currency
+	Quote    document.Currency
+	// Before matches entries for dates before it.
+	Before time.Time
+}
+
+func (f Filter) match(e Entry) bool {
+	if f.Provider != "" && !strings.EqualFold(f.Provider, e.Provider) {
+		return false
+	}
+	if f.Base != "" && f.Base != e.Base {
+		return false
+	}
+	if f.Quote != "" && f.Quote != e.Quote {
+		return false
+	}
+	if !f.Before.IsZero() && e.Date >= f.Before.Format("2006-01-02") {
+		return false
+	}
+	return true
+}
+
+// flushEvery is how many new entries Put buffers before writing the file.
+const flushEvery = 500
+
+// Store keeps rates in a single JSON file. Historical rates never change,
+// so entries do not expire; use Prune to drop them.
+//
+// Put buffers new entries in memory and writes them every flushEvery
+// entries, so call Flush when done.
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]Entry
+	// unsaved counts the entries Put since the file was last written.
+	unsaved int
+}
+
+func NewStore(path string) *Store {
+	return &Store{
+		path: path,
+	}
+}
+
+// DefaultPath returns rates.json in the user's cache directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user cache dir: %w", err)
+	}
+	return filepath.Join(dir, "optimusdime", "rates.json"), nil
+}
+
+func (s *Store) Path() string {
+	return s.path
+}
+
+func (s *Store) Get(provider string, base document.Currency, quote document.Currency, date time.Time) (Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return Entry{}, false, err
+	}
+	e, ok := s.entries[Key(provider, base, quote, date.Format("2006-01-02"))]
+	return e, ok, nil
+}
+
+func (s *Store) Put(e Entry) error {
+	if e.Provider == "" || e.Base == "" || e.Quote == "" || e.Date == "" {
+		return errors.New("cache entry needs a provider, currency pair and date")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if e.FetchedAt.IsZero() {
+		e.FetchedAt = time.Now().UTC()
+	}
+	s.entries[e.key()] = e
+	s.unsaved++
+	if s.unsaved < flushEvery {
+		return nil
+	}
+	return s.save()
+}
+
+// Flush writes the entries Put since the last write, if any.
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.unsaved == 0 {
+		return nil
+	}
+	return s.save()
+}
+
+// List returns the matching entries by provider, pair and date.
+func (s *Store) List(f Filter) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	list := []Entry{}
+	for _, e := range s.entries {
+		if f.match(e) {
+			list = append(list, e)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].key() < list[j].key()
+	})
+	return list, nil
+}
+
+// Prune deletes the matching entries and returns how many there were.
+func (s *Store) Prune(f Filter) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return 0, err
+	}
+	n := 0
+	for k, e := range s.entries {
+		if f.match(e) {
+			delete(s.entries, k)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, s.save()
+}
+
+func (s *Store) load() error {
+	if s.entries != nil {
+		return nil
+	}
+	var list []Entry
+	data, err := os.ReadFile(s.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read rate cache: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &list); err != nil {
+			return fmt.Errorf("failed to parse rate cache in %s: %w", s.path, err)
+		}
+	}
+	s.entries = make(map[string]Entry, len(list))
+	for _, e := range list {
+		s.entries[e.key()] = e
+	}
+	return nil
+}
+
+func (s *Store) save() error {
+	list := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].key() < list[j].key()
+	})
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode rate cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create rate cache dir: %w", err)
+	}
+	// Write to a temporary file first so a crash never leaves a truncated cache.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write rate cache: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write rate cache: %w", err)
+	}
+	s.unsaved = 0
+	return nil
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/converter"
+	"github.com/lazeratops/optimusdime/src/document"
+)
+
+var ErrNotCached = errors.New("rate not in cache")
+
+// Provider is a converter.RateProvider that answers from a Store and only
+// asks the wrapped provider on a miss.
+type Provider struct {
+	store    *Store
+	provider converter.RateProvider
+	offline  bool
+}
+
+func NewProvider(store *Store, provider converter.RateProvider) *Provider {
+	return &Provider{
+		store:    store,
+		provider: provider,
+	}
+}
+
+// SetOffline makes misses fail instead of reaching the wrapped provider.
+func (p *Provider) SetOffline(offline bool) {
+	p.offline = offline
+}
+
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Rate returns the cached rate, or fetches and caches it. Rates for today
+// or later are not cached, as providers still revise them.
+func (p *Provider) Rate(ctx context.Context, from document.Currency, to document.Currency, date time.Time) (converter.Rate, error) {
+	name := p.provider.Name()
+	e, ok, err := p.store.Get(name, from, to, date)
+	if err != nil {
+		return converter.Rate{}, err
+	}
+	if ok {
+		return converter.Rate{
+			From:     from,
+			To:       to,
+			Value:    e.Rate,
+			Date:     e.RateDate,
+			Provider: e.Provider,
+		}, nil
+	}
+	if p.offline {
+		// Also ErrNoRate, so that callers treat it like a gap in the data.
+		return converter.Rate{}, fmt.Errorf("%s %s to %s on %s: %w: %w", name, from, to, date.Format("2006-01-02"), ErrNotCached, converter.ErrNoRate)
+	}
+
+	rate, err := p.provider.Rate(ctx, from, to, date)
+	if err != nil {
+		return converter.Rate{}, err
+	}
+	if !p.cacheable(date) {
+		return rate, nil
+	}
+	rateDate := rate.Date
+	if rateDate.IsZero() {
+		rateDate = date
+	}
+	if err := p.store.Put(Entry{
+		Provider: name,
+		Base:     from,
+		Quote:    to,
+		Date:     date.Format("2006-01-02"),
+		Rate:     rate.Value,
+		RateDate: rateDate,
+	}); err != nil {
+		return converter.Rate{}, err
+	}
+	return rate, nil
+}
+
+// Prefetch asks the wrapped provider for the part of the range that is not
+// cached yet. It does nothing offline, when everything is cached or when
+// the wrapped provider cannot fetch ranges.
+func (p *Provider) Prefetch(ctx context.Context, from document.Currency, to document.Currency, start time.Time, end time.Time) error {
+	rp, ok := p.provider.(converter.RangeProvider)
+	if !ok || p.offline {
+		return nil
+	}
+
+	var first, last time.Time
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		_, ok, err := p.store.Get(p.provider.Name(), from, to, d)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+		if first.IsZero() {
+			first = d
+		}
+		last = d
+	}
+	if first.IsZero() {
+		return nil
+	}
+	return rp.Prefetch(ctx, from, to, first, last)
+}
+
+func (p *Provider) cacheable(date time.Time) bool {
+	y, m, d := time.Now().UTC().Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	y, m, d = date.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Before(today)
+}
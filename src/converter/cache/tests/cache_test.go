@@ -0,0 +1,109 @@
+package cachetest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lazeratops/optimusdime/mocks"
+	"github.com/lazeratops/optimusdime/src/converter"
+	"github.com/lazeratops/optimusdime/src/converter/cache"
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "rates.json")
+	past := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	today := time.Now()
+
+	upstream := mocks.NewRateProvider(t)
+	upstream.On("Name").Return("fake")
+	upstream.On("Rate", mock.Anything, document.SEK, document.EUR, past).Return(converter.Rate{
+		Value:    0.1,
+		Date:     past.AddDate(0, 0, -1),
+		Provider: "fake",
+	}, nil).Once()
+	// Today's rate may still change, so it is fetched every time.
+	upstream.On("Rate", mock.Anything, document.SEK, document.EUR, today).Return(converter.Rate{Value: 0.2, Provider: "fake"}, nil).Twice()
+
+	store := cache.NewStore(path)
+	p := cache.NewProvider(store, upstream)
+	for range 2 {
+		rate, err := p.Rate(context.Background(), document.SEK, document.EUR, past)
+		require.NoError(t, err)
+		require.Equal(t, 0.1, rate.Value)
+		require.Equal(t, past.AddDate(0, 0, -1), rate.Date)
+		require.Equal(t, "fake", rate.Provider)
+
+		_, err = p.Rate(context.Background(), document.SEK, document.EUR, today)
+		require.NoError(t, err)
+	}
+
+	// Rates are only written to disk on Flush. A new store then reads the
+	// rate back, and offline misses fail.
+	list, err := cache.NewStore(path).List(cache.Filter{})
+	require.NoError(t, err)
+	require.Empty(t, list)
+	require.NoError(t, store.Flush())
+
+	offline := cache.NewProvider(cache.NewStore(path), upstream)
+	offline.SetOffline(true)
+	rate, err := offline.Rate(context.Background(), document.SEK, document.EUR, past)
+	require.NoError(t, err)
+	require.Equal(t, 0.1, rate.Value)
+	_, err = offline.Rate(context.Background(), document.USD, document.EUR, past)
+	require.ErrorIs(t, err, cache.ErrNotCached)
+	require.ErrorIs(t, err, converter.ErrNoRate)
+}
+
+func TestStorePrune(t *testing.T) {
+	t.Parallel()
+	store := cache.NewStore(filepath.Join(t.TempDir(), "rates.json"))
+	for _, e := range []cache.Entry{
+		{Provider: "a", Base: document.SEK, Quote: document.EUR, Date: "2024-12-31", Rate: 0.1},
+		{Provider: "a", Base: document.SEK, Quote: document.EUR, Date: "2025-01-02", Rate: 0.1},
+		{Provider: "b", Base: document.SEK, Quote: document.EUR, Date: "2024-12-31", Rate: 0.1},
+	} {
+		require.NoError(t, store.Put(e))
+	}
+
+	n, err := store.Prune(cache.Filter{
+		Provider: "a",
+		Before:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	list, err := store.List(cache.Filter{})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, "2025-01-02", list[0].Date)
+	require.Equal(t, "b", list[1].Provider)
+}
+
+func TestProviderPrefetch(t *testing.T) {
+	t.Parallel()
+	jan := func(day int) time.Time {
+		return time.Date(2025, 1, day, 0, 0, 0, 0, time.UTC)
+	}
+	store := cache.NewStore(filepath.Join(t.TempDir(), "rates.json"))
+	for _, day := range []string{"2025-01-01", "2025-01-02", "2025-01-05"} {
+		require.NoError(t, store.Put(cache.Entry{Provider: "fake", Base: document.SEK, Quote: document.EUR, Date: day, Rate: 0.1}))
+	}
+
+	upstream := mocks.NewRangeProvider(t)
+	upstream.On("Name").Return("fake")
+	// Only the uncached part of the range is prefetched.
+	upstream.On("Prefetch", mock.Anything, document.SEK, document.EUR, jan(3), jan(4)).Return(nil).Once()
+
+	p := cache.NewProvider(store, upstream)
+	require.NoError(t, p.Prefetch(context.Background(), document.SEK, document.EUR, jan(1), jan(5)))
+	require.NoError(t, p.Prefetch(context.Background(), document.SEK, document.EUR, jan(1), jan(2)))
+
+	p.SetOffline(true)
+	require.NoError(t, p.Prefetch(context.Background(), document.SEK, document.EUR, jan(1), jan(10)))
+}
@@ -0,0 +1,118 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/document"
+)
+
+// Chain is a RateProvider that asks its providers in order and returns the
+// first rate found. The rate's Provider names the one that answered. A
+// provider that fails with ErrFatal is not asked again.
+type Chain struct {
+	providers []RateProvider
+
+	mu    sync.Mutex
+	fatal map[int]error
+}
+
+func NewChain(providers ...RateProvider) *Chain {
+	return &Chain{
+		providers: providers,
+		fatal:     make(map[int]error),
+	}
+}
+
+func (c *Chain) Name() string {
+	names := make([]string, len(c.providers))
+	for i, p := range c.providers {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// Rate returns the rate of the first provider that has one. When every
+// provider fails, the error joins all of their errors. It only wraps
+// ErrFatal once every provider has failed with it.
+func (c *Chain) Rate(ctx context.Context, from document.Currency, to document.Currency, date time.Time) (Rate, error) {
+	if len(c.providers) == 0 {
+		return Rate{}, errors.New("no rate providers configured")
+	}
+
+	var errs []error
+	usable := false
+	for i, p := range c.providers {
+		c.mu.Lock()
+		fatal := c.fatal[i]
+		c.mu.Unlock()
+		if fatal != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), fatal))
+			continue
+		}
+
+		rate, err := p.Rate(ctx, from, to, date)
+		if errors.Is(err, ErrFatal) {
+			c.mu.Lock()
+			if c.fatal[i] == nil {
+				log.Printf("\n Not asking %s for rates any more: %v", p.Name(), err)
+				c.fatal[i] = err
+			}
+			c.mu.Unlock()
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+			continue
+		}
+		if err != nil {
+			usable = true
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+			continue
+		}
+		if rate.Provider == "" {
+			rate.Provider = p.Name()
+		}
+		return rate, nil
+	}
+	if usable {
+		// Only the other providers' errors decide what the caller does.
+		return Rate{}, errors.Join(withoutFatal(errs)...)
+	}
+	return Rate{}, errors.Join(errs...)
+}
+
+// withoutFatal keeps the messages of ErrFatal errors but not the errors,
+// so that errors.Is does not find ErrFatal in them.
+func withoutFatal(errs []error) []error {
+	kept := make([]error, len(errs))
+	for i, err := range errs {
+		kept[i] = err
+		if errors.Is(err, ErrFatal) {
+			kept[i] = errors.New(err.Error())
+		}
+	}
+	return kept
+}
+
+// Prefetch prefetches with the first provider, as it answers most
+// lookups, and moves on to the next only when it fails, like Rate.
+// Providers that cannot fetch ranges end the search, so that later ones
+// do not spend requests on rates they will likely not be asked for.
+func (c *Chain) Prefetch(ctx context.Context, from document.Currency, to document.Currency, start time.Time, end time.Time) error {
+	var errs []error
+	for _, p := range c.providers {
+		rp, ok := p.(RangeProvider)
+		if !ok {
+			return nil
+		}
+		err := rp.Prefetch(ctx, from, to, start, end)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+	}
+	return errors.Join(errs...)
+}
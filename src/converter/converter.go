@@ -1,9 +1,11 @@
 package converter
 
 import (
+	"context"
+
 	"github.com/lazeratops/optimusdime/src/document"
 )
 
 type Converter interface {
-	Convert(targetCurrency document.Currency, statement *document.Document) (*document.Document, *document.Document, error)
+	Convert(ctx context.Context, targetCurrency document.Currency, statement *document.Document) (*document.Document, *document.Document, error)
 }
@@ -1,37 +1,58 @@
 package currencylayer
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"log"
-	"math"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lazeratops/optimusdime/src/converter"
 	"github.com/lazeratops/optimusdime/src/document"
 )
 
-const defaultApiUrl = "api.currencylayer.com/historical"
+const (
+	defaultApiUrl = "api.currencylayer.com/historical"
+	providerName  = "currencylayer"
+	// source is the currency all quotes are against, the only one the
+	// free plan allows.
+	source = document.USD
+	// maxTimeframeDays is the longest range the timeframe endpoint serves.
+	maxTimeframeDays = 365
+)
 
 type Api struct {
-	apiKey string
-	url    string
-	schema string
+	apiKey   string
+	url      string
+	schema   string
+	rounding document.RoundingMode
+	client   *http.Client
+	retry    converter.RetryPolicy
+	plan     Plan
+
+	mu sync.Mutex
+	// timeframeRestricted is set when the API refused the timeframe
+	// endpoint despite the plan.
+	timeframeRestricted bool
+	// quotesByDate maps dates (YYYY-MM-DD) to their USD quotes.
+	quotesByDate map[string]map[string]float64
+	fetches      converter.Memo[string, *ApiResponse]
+	timeframes   converter.Memo[string, *TimeframeResponse]
 }
 
 func NewCurrencyLayer(apiUrl string, apiKey string) (*Api, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("API key must be provided for CurrencyLayer API")
 	}
+	// Without a scheme in apiUrl, the plan decides.
 	var schema string
 	if apiUrl == "" {
 		apiUrl = defaultApiUrl
-		schema = "https"
 	} else {
 
 		// Parse URL
@@ -46,98 +67,262 @@ func NewCurrencyLayer(apiUrl string, apiKey string) (*Api, error) {
 	}
 
 	return &Api{
-		url:    apiUrl,
-		schema: schema,
-		apiKey: apiKey,
+		url:          apiUrl,
+		schema:       schema,
+		apiKey:       apiKey,
+		retry:        converter.DefaultRetryPolicy,
+		quotesByDate: make(map[string]map[string]float64),
 	}, nil
 }
 
-func (api *Api) Convert(targetCurrency document.Currency, statement *document.Document) (*document.Document, *document.Document, error) {
-	if len(statement.Transactions) == 0 {
-		return nil, nil, errors.New("no transactions to convert")
-	}
+// SetRounding sets how converted amounts are rounded to the minor units of
+// the target currency. The default is document.HalfEven.
+func (api *Api) SetRounding(mode document.RoundingMode) {
+	api.rounding = mode
+}
 
-	newDoc := document.Document{
-		Transactions: []document.Transaction{},
-	}
+// SetHttpClient sets the client for API requests. The default is
+// converter.DefaultHttpClient.
+func (api *Api) SetHttpClient(client *http.Client) {
+	api.client = client
+}
 
-	failedToConvertDoc := document.Document{
-		Transactions: []document.Transaction{},
-	}
+// SetRetryPolicy sets how failed API requests are retried. The default is
+// converter.DefaultRetryPolicy.
+func (api *Api) SetRetryPolicy(policy converter.RetryPolicy) {
+	api.retry = policy
+}
+
+// SetPlan sets the subscription plan of the API key. The default is
+// PlanFree, which requests over plain HTTP and never uses the timeframe
+// endpoint.
+func (api *Api) SetPlan(plan Plan) {
+	api.plan = plan
+}
 
-	type dateCurrenciesTransactions struct {
-		currencies   []document.Currency
-		transactions []document.Transaction
+// Convert converts statement with currencylayer rates.
+func (api *Api) Convert(ctx context.Context, targetCurrency document.Currency, statement *document.Document) (*document.Document, *document.Document, error) {
+	c := converter.NewRateConverter(api)
+	c.SetRounding(api.rounding)
+	return c.Convert(ctx, targetCurrency, statement)
+}
+
+func (api *Api) Name() string {
+	return providerName
+}
+
+// Rate returns the cross rate from one currency to another via USD, the
+// only source currency of the free plan. Errors the API reports wrap
+// ApiError.
+func (api *Api) Rate(ctx context.Context, from document.Currency, to document.Currency, date time.Time) (converter.Rate, error) {
+	quotes, err := api.quotes(ctx, date, from, to)
+	if err != nil {
+		return converter.Rate{}, err
 	}
 
-	sourceCurrenciesForDate := make(map[time.Time]dateCurrenciesTransactions)
+	rate, err := quotes.getCrossRate(from, to)
+	if err != nil {
+		return converter.Rate{}, err
+	}
+	return converter.Rate{
+		From:     from,
+		To:       to,
+		Value:    rate,
+		Date:     date,
+		Provider: providerName,
+	}, nil
+}
 
-	for _, oldTransaction := range statement.Transactions {
-		sourceCurrenciesForDate[oldTransaction.Date] = dateCurrenciesTransactions{
-			transactions: append(sourceCurrenciesForDate[oldTransaction.Date].transactions, oldTransaction),
-			currencies:   append(sourceCurrenciesForDate[oldTransaction.Date].currencies, oldTransaction.Currency),
+// quotes returns the USD quotes of date for the given currencies. Quotes
+// are kept per date, so a statement costs one request per date unless it
+// has transactions in several currencies.
+func (api *Api) quotes(ctx context.Context, date time.Time, currencies ...document.Currency) (*ApiResponse, error) {
+	day := date.Format("2006-01-02")
+	if missing := api.missing(day, currencies); len(missing) > 0 {
+		key := day + "|" + strings.Join(missing, ",")
+		res, err := api.fetches.Do(key, func() (*ApiResponse, error) {
+			return api.fetchQuotes(ctx, date, missing)
+		})
+		if err != nil {
+			return nil, err
 		}
+		api.storeQuotes(day, res.Quotes)
 	}
 
-	var lastError error
-	for date, v := range sourceCurrenciesForDate {
-		c := document.Currency(targetCurrency)
-		url := api.getUrl()
-		if url == "" {
-			return nil, nil, fmt.Errorf("failed to get api URL for date %v and currency %v", date, c)
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	quotes := &ApiResponse{
+		Source: source,
+		Quotes: make(map[string]float64),
+	}
+	for _, c := range currencies {
+		pair := fmt.Sprintf("USD%s", c)
+		if q, ok := api.quotesByDate[day][pair]; ok {
+			quotes.Quotes[pair] = q
 		}
-		v.currencies = append(v.currencies, targetCurrency)
-		resBody, err := api.fetch(url, v.currencies, date)
-		if err != nil {
-			failedToConvertDoc.Transactions = append(failedToConvertDoc.Transactions, v.transactions...)
-			log.Printf("\n Failed to fetch transactions from URL: %s: %v", url, err)
-			lastError = err
+	}
+	return quotes, nil
+}
+
+// missing returns the currencies without a USD quote on day.
+func (api *Api) missing(day string, currencies []document.Currency) []string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	var missing []string
+	for _, c := range currencies {
+		if c == source {
 			continue
 		}
+		if _, ok := api.quotesByDate[day][fmt.Sprintf("USD%s", c)]; !ok {
+			missing = append(missing, string(c))
+		}
+	}
+	return missing
+}
 
-		var currencyRes ApiResponse
-		if err := json.Unmarshal(resBody, &currencyRes); err != nil {
-			failedToConvertDoc.Transactions = append(failedToConvertDoc.Transactions, v.transactions...)
-			log.Printf("\n Failed to parse currency response from URL: %s: %v", url, err)
-			lastError = err
+func (api *Api) storeQuotes(day string, quotes map[string]float64) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if api.quotesByDate[day] == nil {
+		api.quotesByDate[day] = make(map[string]float64)
+	}
+	for pair, quote := range quotes {
+		api.quotesByDate[day][pair] = quote
+	}
+}
+
+// Prefetch fetches the USD quotes of both currencies from start to end
+// from the timeframe endpoint, a year per request. Dates that already have
+// their quotes are left out of the range. It does nothing unless the plan
+// has the timeframe endpoint.
+func (api *Api) Prefetch(ctx context.Context, from document.Currency, to document.Currency, start time.Time, end time.Time) error {
+	api.mu.Lock()
+	restricted := api.timeframeRestricted
+	api.mu.Unlock()
+	if !api.plan.timeframe() || restricted {
+		return nil
+	}
+	currencies := []document.Currency{from, to}
+
+	var first, last time.Time
+	var missing []string
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		m := api.missing(d.Format("2006-01-02"), currencies)
+		if len(m) == 0 {
 			continue
 		}
+		if first.IsZero() {
+			first = d
+		}
+		last = d
+		for _, c := range m {
+			if !slices.Contains(missing, c) {
+				missing = append(missing, c)
+			}
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
 
-		if currencyRes.Source != document.USD {
-			return nil, nil, fmt.Errorf("unexpected currency response from CurrencyLayer. Expected USD source, got %s", currencyRes.Source)
+	for chunkStart := first; !chunkStart.After(last); chunkStart = chunkStart.AddDate(0, 0, maxTimeframeDays) {
+		chunkEnd := chunkStart.AddDate(0, 0, maxTimeframeDays-1)
+		if chunkEnd.After(last) {
+			chunkEnd = last
+		}
+		key := "timeframe|" + chunkStart.Format("2006-01-02") + "|" + chunkEnd.Format("2006-01-02") + "|" + strings.Join(missing, ",")
+		res, err := api.timeframes.Do(key, func() (*TimeframeResponse, error) {
+			return api.fetchTimeframe(ctx, chunkStart, chunkEnd, missing)
+		})
+		if errors.Is(err, ErrFunctionRestricted) {
+			// Lookups by date still work, so only stop prefetching.
+			api.mu.Lock()
+			api.timeframeRestricted = true
+			api.mu.Unlock()
+			return fmt.Errorf("%w (is the plan really %s?)", err, api.plan)
+		}
+		if err != nil {
+			return err
 		}
+		for day, quotes := range res.Quotes {
+			api.storeQuotes(day, quotes)
+		}
+	}
+	return nil
+}
 
-		for _, oldTransaction := range v.transactions {
-			rate, err := currencyRes.getCrossRate(oldTransaction.Currency, targetCurrency)
-			if err != nil {
-				failedToConvertDoc.Transactions = append(failedToConvertDoc.Transactions, oldTransaction)
-				log.Printf("\nFailed to get cross rate for %s to %s: %v", oldTransaction.Currency, targetCurrency, err)
-				continue
-			}
+func (api *Api) fetchQuotes(ctx context.Context, date time.Time, currencies []string) (*ApiResponse, error) {
+	url := api.getUrl()
+	resBody, err := api.fetch(ctx, url, currencies, map[string]string{
+		"date": date.Format("2006-01-02"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rates from URL %s: %w", url, err)
+	}
 
-			convertedAmount := oldTransaction.Amount * rate
-			convertedAmount = math.Round(convertedAmount*100) / 100
+	var currencyRes ApiResponse
+	if err := json.Unmarshal(resBody, &currencyRes); err != nil {
+		return nil, fmt.Errorf("failed to parse currency response from URL %s: %w", url, err)
+	}
+	if err := checkResponse(currencyRes.Success, currencyRes.Error, currencyRes.Source); err != nil {
+		return nil, err
+	}
+	return &currencyRes, nil
+}
 
-			transaction := &document.Transaction{
-				Description: oldTransaction.Description,
-				Currency:    targetCurrency,
-				Date:        oldTransaction.Date,
-				Amount:      convertedAmount,
-			}
-			newDoc.Transactions = append(newDoc.Transactions, *transaction)
+func (api *Api) fetchTimeframe(ctx context.Context, start time.Time, end time.Time, currencies []string) (*TimeframeResponse, error) {
+	url := api.getTimeframeUrl()
+	resBody, err := api.fetch(ctx, url, currencies, map[string]string{
+		"start_date": start.Format("2006-01-02"),
+		"end_date":   end.Format("2006-01-02"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rates from URL %s: %w", url, err)
+	}
+
+	var currencyRes TimeframeResponse
+	if err := json.Unmarshal(resBody, &currencyRes); err != nil {
+		return nil, fmt.Errorf("failed to parse currency response from URL %s: %w", url, err)
+	}
+	if err := checkResponse(currencyRes.Success, currencyRes.Error, currencyRes.Source); err != nil {
+		return nil, err
+	}
+	return &currencyRes, nil
+}
+
+// checkResponse returns the error of a response with "success": false,
+// and ErrInvalidSource for quotes against anything but USD.
+func checkResponse(success bool, apiErr *ApiError, responseSource document.Currency) error {
+	if !success {
+		if apiErr == nil {
+			return fmt.Errorf("currencylayer request failed without an error object: %w", converter.ErrFailedAPICall)
 		}
+		return apiErr
 	}
-	if len(newDoc.Transactions) == 0 && lastError != nil {
-		return nil, &failedToConvertDoc, lastError
+	if responseSource != source {
+		return fmt.Errorf("got quotes against %s, want %s: %w: %w", responseSource, source, ErrInvalidSource, converter.ErrFatal)
 	}
-	return &newDoc, &failedToConvertDoc, nil
+	return nil
 }
 
 func (api *Api) getUrl() string {
-	return fmt.Sprintf("%s://%s", api.schema, api.url)
+	return fmt.Sprintf("%s://%s", api.getSchema(), api.url)
+}
+
+// getTimeframeUrl returns the timeframe endpoint next to the historical
+// one.
+func (api *Api) getTimeframeUrl() string {
+	return fmt.Sprintf("%s://%s/timeframe", api.getSchema(), strings.TrimSuffix(api.url, "/historical"))
 }
 
-func (api *Api) fetch(apiUrl string, currencies []document.Currency, date time.Time) ([]byte, error) {
+func (api *Api) getSchema() string {
+	if api.schema != "" {
+		return api.schema
+	}
+	return api.plan.schema()
+}
+
+func (api *Api) fetch(ctx context.Context, apiUrl string, currencies []string, dates map[string]string) ([]byte, error) {
 	// Create base URL
 	baseURL, err := url.Parse(apiUrl)
 	if err != nil {
@@ -146,33 +331,15 @@ func (api *Api) fetch(apiUrl string, currencies []document.Currency, date time.T
 
 	params := baseURL.Query()
 	params.Add("access_key", api.apiKey)
-	params.Add("date", date.Format("2006-01-02"))
+	for name, date := range dates {
+		params.Add(name, date)
+	}
 
 	if len(currencies) > 0 {
-		currencyStrs := make([]string, len(currencies))
-		for i, c := range currencies {
-			currencyStrs[i] = string(c)
-		}
-		params.Add("currencies", strings.Join(currencyStrs, ","))
+		params.Add("currencies", strings.Join(currencies, ","))
 	}
 
 	baseURL.RawQuery = params.Encode()
 
-	// Make the request
-	resp, err := http.Get(baseURL.String())
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch data: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("api returned error %d: %s: %w", resp.StatusCode, resp.Status, converter.ErrFailedAPICall)
-	}
-
-	return body, nil
+	return converter.Fetch(ctx, api.client, api.retry, baseURL.String())
 }
@@ -0,0 +1,57 @@
+package currencylayer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lazeratops/optimusdime/src/converter"
+)
+
+var (
+	ErrInvalidKey = errors.New("invalid or inactive currencylayer API key")
+	ErrQuota      = errors.New("currencylayer monthly request quota reached")
+	// ErrHttpsRestricted means the plan, like the free one, only allows
+	// plain HTTP.
+	ErrHttpsRestricted = errors.New("currencylayer plan does not allow HTTPS")
+	// ErrFunctionRestricted means the plan does not have an endpoint, such
+	// as timeframe.
+	ErrFunctionRestricted = errors.New("currencylayer plan does not allow this endpoint")
+	// ErrInvalidSource means the source currency is not allowed, as on
+	// the free plan for anything but USD.
+	ErrInvalidSource = errors.New("invalid currencylayer source currency")
+)
+
+// ApiError is the error object of a response with "success": false.
+// currencylayer sends these with a 200 status.
+type ApiError struct {
+	Code int    `json:"code"`
+	Type string `json:"type"`
+	Info string `json:"info"`
+}
+
+func (e *ApiError) Error() string {
+	return fmt.Sprintf("currencylayer error %d (%s): %s", e.Code, e.Type, e.Info)
+}
+
+// Unwrap maps the documented error codes to this package's errors, and to
+// converter.ErrFatal for the ones no later request can succeed after.
+func (e *ApiError) Unwrap() []error {
+	switch e.Code {
+	case 101, 102:
+		return []error{ErrInvalidKey, converter.ErrFatal}
+	case 104:
+		return []error{ErrQuota, converter.ErrFatal}
+	case 105:
+		if strings.Contains(e.Type, "https") {
+			return []error{ErrHttpsRestricted, converter.ErrFatal}
+		}
+		return []error{ErrFunctionRestricted, converter.ErrFatal}
+	case 201:
+		return []error{ErrInvalidSource, converter.ErrFatal}
+	case 106, 202, 302:
+		// No rates for the date, or unknown currency codes.
+		return []error{converter.ErrNoRate}
+	}
+	return []error{converter.ErrFailedAPICall}
+}
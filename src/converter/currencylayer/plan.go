@@ -0,0 +1,57 @@
+package currencylayer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Plan is a currencylayer subscription plan, which decides what the API
+// allows. Rates are crossed through USD on every plan, as the free plan
+// has no other source currency.
+type Plan int
+
+const (
+	// PlanFree only has the historical endpoint, over plain HTTP.
+	PlanFree Plan = iota
+	// PlanBasic adds HTTPS.
+	PlanBasic
+	// PlanProfessional, and the plans above it, add the timeframe
+	// endpoint.
+	PlanProfessional
+)
+
+func (p Plan) String() string {
+	switch p {
+	case PlanFree:
+		return "free"
+	case PlanBasic:
+		return "basic"
+	case PlanProfessional:
+		return "professional"
+	}
+	return fmt.Sprintf("Plan(%d)", int(p))
+}
+
+func ParsePlan(s string) (Plan, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "free", "":
+		return PlanFree, nil
+	case "basic":
+		return PlanBasic, nil
+	case "professional", "business", "enterprise":
+		return PlanProfessional, nil
+	}
+	return 0, fmt.Errorf("unknown currencylayer plan %q (want free, basic or professional)", s)
+}
+
+// schema returns the URL scheme the plan allows.
+func (p Plan) schema() string {
+	if p == PlanFree {
+		return "http"
+	}
+	return "https"
+}
+
+func (p Plan) timeframe() bool {
+	return p >= PlanProfessional
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/lazeratops/optimusdime/src/converter"
 	"github.com/lazeratops/optimusdime/src/document"
 )
 
@@ -27,6 +28,7 @@ func (ut UnixTimestamp) Time() time.Time {
 
 type ApiResponse struct {
 	Success    bool               `json:"success"`
+	Error      *ApiError          `json:"error"`
 	Historical bool               `json:"historical"`
 	Date       UnixTimestamp      `json:"date"`
 	Timestamp  int                `json:"timestamp"`
@@ -34,6 +36,18 @@ type ApiResponse struct {
 	Quotes     map[string]float64 `json:"quotes"`
 }
 
+// TimeframeResponse holds the USD quotes of every date in a range, keyed by
+// date (YYYY-MM-DD).
+type TimeframeResponse struct {
+	Success   bool                          `json:"success"`
+	Error     *ApiError                     `json:"error"`
+	Timeframe bool                          `json:"timeframe"`
+	StartDate string                        `json:"start_date"`
+	EndDate   string                        `json:"end_date"`
+	Source    document.Currency             `json:"source"`
+	Quotes    map[string]map[string]float64 `json:"quotes"`
+}
+
 func (r *ApiResponse) getCrossRate(sourceCurrency, targetCurrency document.Currency) (float64, error) {
 	if sourceCurrency == document.USD {
 		return r.getUsdTargetRate(targetCurrency)
@@ -41,7 +55,7 @@ func (r *ApiResponse) getCrossRate(sourceCurrency, targetCurrency document.Curre
 	// Get USD to source rate
 	usdSourceRate, exists := r.Quotes[fmt.Sprintf("USD%s", sourceCurrency.String())]
 	if !exists {
-		return 0, fmt.Errorf("failed to find rate for USD to %s", sourceCurrency)
+		return 0, fmt.Errorf("failed to find rate for USD to %s: %w", sourceCurrency, converter.ErrNoRate)
 	}
 
 	// Get USD to target rate
@@ -57,9 +71,12 @@ func (r *ApiResponse) getCrossRate(sourceCurrency, targetCurrency document.Curre
 }
 
 func (r *ApiResponse) getUsdTargetRate(targetCurrency document.Currency) (float64, error) {
+	if targetCurrency == document.USD {
+		return 1, nil
+	}
 	usdTargetRate, exists := r.Quotes[fmt.Sprintf("USD%s", targetCurrency.String())]
 	if !exists {
-		return 0, fmt.Errorf("failed to find rate for USD to %s", targetCurrency)
+		return 0, fmt.Errorf("failed to find rate for USD to %s: %w", targetCurrency, converter.ErrNoRate)
 	}
 	return usdTargetRate, nil
 }
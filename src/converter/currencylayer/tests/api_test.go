@@ -1,9 +1,14 @@
 package apitest
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -40,7 +45,7 @@ func TestConvert(t *testing.T) {
 					{
 						Description: "transaction1",
 						Currency:    document.SEK,
-						Amount:      100,
+						Amount:      document.MustParseMoney("100.00"),
 						Date:        date_20250101,
 					},
 				},
@@ -69,7 +74,7 @@ func TestConvert(t *testing.T) {
 					{
 						Description: "transaction1",
 						Currency:    document.SEK,
-						Amount:      1124.233239,
+						Amount:      document.MustParseMoney("1124.23"),
 						Date:        date_20250101,
 					},
 				},
@@ -80,8 +85,15 @@ func TestConvert(t *testing.T) {
 					{
 						Description: "transaction1",
 						Currency:    document.EUR,
-						Amount:      100,
+						Amount:      document.MustParseMoney("100.00"),
 						Date:        date_20250101,
+						Conversion: &document.Conversion{
+							OriginalAmount:   document.MustParseMoney("1124.23"),
+							OriginalCurrency: document.SEK,
+							Rate:             1 / 11.24233239,
+							RateDate:         date_20250101,
+							RateProvider:     "currencylayer",
+						},
 					},
 				},
 			},
@@ -108,7 +120,7 @@ func TestConvert(t *testing.T) {
 			api, err := currencylayer.NewCurrencyLayer(testServer.URL, "some-key")
 			require.NoError(t, err)
 
-			gotDoc, failedDoc, gotErr := api.Convert(tc.targetCurrency, tc.sourceDocument)
+			gotDoc, failedDoc, gotErr := api.Convert(context.Background(), tc.targetCurrency, tc.sourceDocument)
 			require.ErrorIs(t, gotErr, tc.wantErr)
 			if gotErr == nil {
 				require.EqualValues(t, tc.wantDocument, gotDoc)
@@ -117,3 +129,181 @@ func TestConvert(t *testing.T) {
 		})
 	}
 }
+
+func TestTimeframe(t *testing.T) {
+	t.Parallel()
+	var requests []string
+	var mu sync.Mutex
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/timeframe", r.URL.Path)
+		query := r.URL.Query()
+		require.Equal(t, "some-key", query.Get("access_key"))
+		start, err := time.Parse("2006-01-02", query.Get("start_date"))
+		require.NoError(t, err)
+		end, err := time.Parse("2006-01-02", query.Get("end_date"))
+		require.NoError(t, err)
+		mu.Lock()
+		requests = append(requests, query.Get("start_date")+"/"+query.Get("end_date")+"/"+query.Get("currencies"))
+		mu.Unlock()
+
+		quotes := map[string]map[string]float64{}
+		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+			quotes[d.Format("2006-01-02")] = map[string]float64{"USDEUR": 0.9, "USDSEK": 10}
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"success":    true,
+			"timeframe":  true,
+			"start_date": query.Get("start_date"),
+			"end_date":   query.Get("end_date"),
+			"source":     "USD",
+			"quotes":     quotes,
+		}))
+	}))
+	defer testServer.Close()
+
+	api, err := currencylayer.NewCurrencyLayer(testServer.URL, "some-key")
+	require.NoError(t, err)
+	api.SetPlan(currencylayer.PlanProfessional)
+
+	day1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	day3 := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+	gotDoc, failedDoc, err := api.Convert(context.Background(), document.EUR, &document.Document{
+		Transactions: []document.Transaction{
+			{Description: "a", Currency: document.SEK, Amount: document.MustParseMoney("100.00"), Date: day3},
+			{Description: "b", Currency: document.SEK, Amount: document.MustParseMoney("200.00"), Date: day1},
+			{Description: "c", Currency: document.USD, Amount: document.MustParseMoney("10.00"), Date: day1},
+		},
+	})
+	require.NoError(t, err)
+	require.Empty(t, failedDoc.Transactions)
+	require.Equal(t, "9.00", gotDoc.Transactions[0].Amount.String())
+	require.Equal(t, "18.00", gotDoc.Transactions[1].Amount.String())
+	require.Equal(t, "9.00", gotDoc.Transactions[2].Amount.String())
+	// The SEK span covers both currencies; USD's date is already known.
+	require.Equal(t, []string{"2025-01-01/2025-01-03/SEK,EUR"}, requests)
+
+	// Ranges are fetched a year at a time, leaving out known dates.
+	requests = nil
+	require.NoError(t, api.Prefetch(context.Background(), document.SEK, document.EUR, day1, day1.AddDate(0, 0, 400)))
+	require.Equal(t, []string{"2025-01-04/2026-01-03/SEK,EUR", "2026-01-04/2026-02-05/SEK,EUR"}, requests)
+}
+
+func TestApiErrors(t *testing.T) {
+	t.Parallel()
+	date := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		apiBody   string
+		wantErr   error
+		wantFatal bool
+	}{
+		{
+			name:      "invalid key",
+			apiBody:   `{"success": false, "error": {"code": 101, "type": "invalid_access_key", "info": "You have not supplied a valid API Access Key."}}`,
+			wantErr:   currencylayer.ErrInvalidKey,
+			wantFatal: true,
+		},
+		{
+			name:      "quota",
+			apiBody:   `{"success": false, "error": {"code": 104, "type": "usage_limit_reached", "info": "Your monthly usage limit has been reached."}}`,
+			wantErr:   currencylayer.ErrQuota,
+			wantFatal: true,
+		},
+		{
+			name:      "https restricted",
+			apiBody:   `{"success": false, "error": {"code": 105, "type": "https_access_restricted", "info": "Access Restricted - Your current Subscription Plan does not support HTTPS Encryption."}}`,
+			wantErr:   currencylayer.ErrHttpsRestricted,
+			wantFatal: true,
+		},
+		{
+			name:      "invalid source",
+			apiBody:   `{"success": false, "error": {"code": 201, "type": "invalid_source_currency", "info": "You have supplied an invalid Source Currency."}}`,
+			wantErr:   currencylayer.ErrInvalidSource,
+			wantFatal: true,
+		},
+		{
+			name:      "unexpected source",
+			apiBody:   `{"success": true, "source": "EUR", "quotes": {"EURSEK": 11.5}}`,
+			wantErr:   currencylayer.ErrInvalidSource,
+			wantFatal: true,
+		},
+		{
+			name:    "invalid currency",
+			apiBody: `{"success": false, "error": {"code": 202, "type": "invalid_currency_codes", "info": "You have provided one or more invalid Currency Codes."}}`,
+			wantErr: converter.ErrNoRate,
+		},
+		{
+			name:    "unknown code",
+			apiBody: `{"success": false, "error": {"code": 999, "type": "something_new", "info": "Something went wrong."}}`,
+			wantErr: converter.ErrFailedAPICall,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var requests atomic.Int32
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				_, err := w.Write([]byte(tc.apiBody))
+				require.NoError(t, err)
+			}))
+			defer testServer.Close()
+
+			api, err := currencylayer.NewCurrencyLayer(testServer.URL, "some-key")
+			require.NoError(t, err)
+
+			_, err = api.Rate(context.Background(), document.SEK, document.EUR, date)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantFatal, errors.Is(err, converter.ErrFatal))
+
+			// Conversions stop at the first fatal error instead of asking
+			// again for every date.
+			c := converter.NewRateConverter(api)
+			c.SetParallelism(1)
+			requests.Store(0)
+			_, _, err = c.Convert(context.Background(), document.EUR, &document.Document{
+				Transactions: []document.Transaction{
+					{Description: "a", Currency: document.SEK, Amount: document.MustParseMoney("1.00"), Date: date},
+					{Description: "b", Currency: document.SEK, Amount: document.MustParseMoney("1.00"), Date: date.AddDate(0, 0, 1)},
+					{Description: "c", Currency: document.SEK, Amount: document.MustParseMoney("1.00"), Date: date.AddDate(0, 0, 2)},
+				},
+			})
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantFatal {
+				require.Equal(t, int32(1), requests.Load())
+			} else {
+				require.Equal(t, int32(3), requests.Load())
+			}
+		})
+	}
+}
+
+func TestTimeframeRestricted(t *testing.T) {
+	t.Parallel()
+	var requests atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, err := w.Write([]byte(`{"success": false, "error": {"code": 105, "type": "function_access_restricted", "info": "Access Restricted - Your current Subscription Plan does not support this API Function."}}`))
+		require.NoError(t, err)
+	}))
+	defer testServer.Close()
+
+	api, err := currencylayer.NewCurrencyLayer(testServer.URL, "some-key")
+	require.NoError(t, err)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 10)
+
+	// Only professional plans have the timeframe endpoint.
+	api.SetPlan(currencylayer.PlanBasic)
+	require.NoError(t, api.Prefetch(context.Background(), document.SEK, document.EUR, start, end))
+	require.Equal(t, int32(0), requests.Load())
+
+	// A refused timeframe request is not repeated.
+	api.SetPlan(currencylayer.PlanProfessional)
+	err = api.Prefetch(context.Background(), document.SEK, document.EUR, start, end)
+	require.ErrorIs(t, err, currencylayer.ErrFunctionRestricted)
+	require.NoError(t, api.Prefetch(context.Background(), document.USD, document.EUR, start, end))
+	require.Equal(t, int32(1), requests.Load())
+}
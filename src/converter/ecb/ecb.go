@@ -0,0 +1,280 @@
+package ecb
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/converter"
+	"github.com/lazeratops/optimusdime/src/document"
+)
+
+const (
+	// DefaultSource is the ECB's full history of euro reference rates.
+	DefaultSource = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist.xml"
+	providerName  = "ecb"
+)
+
+// Api provides the ECB euro foreign exchange reference rates from one of
+// the published history files: eurofxref-hist.xml, eurofxref-hist.csv or
+// eurofxref-hist.zip. Rates against anything but EUR are crossed through
+// EUR. The ECB publishes on TARGET business days only, so weekends and
+// holidays have no rate.
+type Api struct {
+	source   string
+	rounding document.RoundingMode
+	client   *http.Client
+	retry    converter.RetryPolicy
+
+	mu sync.Mutex
+	// rates maps a date (YYYY-MM-DD) to the units of each currency per EUR.
+	rates map[string]map[document.Currency]float64
+	// loadErr is why the rates could not be read. It is returned for the
+	// rest of the run instead of reading the file again on every lookup.
+	loadErr error
+}
+
+// NewEcb reads rates from source, a local path or an http(s) URL. The file
+// is read on first use. An empty source means DefaultSource.
+func NewEcb(source string) (*Api, error) {
+	if source == "" {
+		source = DefaultSource
+	}
+	return &Api{
+		source: source,
+		retry:  converter.DefaultRetryPolicy,
+	}, nil
+}
+
+// SetRounding sets how converted amounts are rounded to the minor units of
+// the target currency. The default is document.HalfEven.
+func (api *Api) SetRounding(mode document.RoundingMode) {
+	api.rounding = mode
+}
+
+// SetHttpClient sets the client for API requests. The default is
+// converter.DefaultHttpClient.
+func (api *Api) SetHttpClient(client *http.Client) {
+	api.client = client
+}
+
+// SetRetryPolicy sets how failed API requests are retried. The default is
+// converter.DefaultRetryPolicy.
+func (api *Api) SetRetryPolicy(policy converter.RetryPolicy) {
+	api.retry = policy
+}
+
+// Convert converts statement with ECB reference rates.
+func (api *Api) Convert(ctx context.Context, targetCurrency document.Currency, statement *document.Document) (*document.Document, *document.Document, error) {
+	c := converter.NewRateConverter(api)
+	c.SetRounding(api.rounding)
+	return c.Convert(ctx, targetCurrency, statement)
+}
+
+func (api *Api) Name() string {
+	return providerName
+}
+
+func (api *Api) Rate(ctx context.Context, from document.Currency, to document.Currency, date time.Time) (converter.Rate, error) {
+	rates, err := api.load(ctx)
+	if err != nil {
+		return converter.Rate{}, err
+	}
+
+	day := date.Format("2006-01-02")
+	dayRates, ok := rates[day]
+	if !ok {
+		return converter.Rate{}, fmt.Errorf("no ECB reference rates on %s: %w", day, converter.ErrNoRate)
+	}
+	perEur := func(c document.Currency) (float64, error) {
+		if c == document.EUR {
+			return 1, nil
+		}
+		r, ok := dayRates[c]
+		if !ok || r == 0 {
+			return 0, fmt.Errorf("no ECB reference rate for %s on %s: %w", c, day, converter.ErrNoRate)
+		}
+		return r, nil
+	}
+	fromRate, err := perEur(from)
+	if err != nil {
+		return converter.Rate{}, err
+	}
+	toRate, err := perEur(to)
+	if err != nil {
+		return converter.Rate{}, err
+	}
+
+	return converter.Rate{
+		From:     from,
+		To:       to,
+		Value:    toRate / fromRate,
+		Date:     date,
+		Provider: providerName,
+	}, nil
+}
+
+// Prefetch loads the rates. The history holds every date, so one read
+// serves any range.
+func (api *Api) Prefetch(ctx context.Context, from document.Currency, to document.Currency, start time.Time, end time.Time) error {
+	_, err := api.load(ctx)
+	return err
+}
+
+func (api *Api) load(ctx context.Context) (map[string]map[document.Currency]float64, error) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if api.rates != nil || api.loadErr != nil {
+		return api.rates, api.loadErr
+	}
+
+	data, err := api.read(ctx)
+	if err != nil {
+		// A cancelled lookup says nothing about the source.
+		if ctx.Err() == nil {
+			api.loadErr = err
+		}
+		return nil, err
+	}
+	rates, err := parse(data)
+	if err != nil {
+		api.loadErr = fmt.Errorf("failed to parse ECB rates from %s: %w", api.source, err)
+		return nil, api.loadErr
+	}
+	api.rates = rates
+	return rates, nil
+}
+
+func (api *Api) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(api.source, "http://") && !strings.HasPrefix(api.source, "https://") {
+		data, err := os.ReadFile(api.source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ECB rates: %w", err)
+		}
+		return data, nil
+	}
+
+	return converter.Fetch(ctx, api.client, api.retry, api.source)
+}
+
+// parse reads any of the history file formats, told apart by content as
+// file names and content types are not reliable.
+func parse(data []byte) (map[string]map[document.Currency]float64, error) {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("PK")):
+		return parseZip(data)
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return parseXml(trimmed)
+	}
+	return parseCsv(trimmed)
+}
+
+func parseZip(data []byte) (map[string]map[document.Currency]float64, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range r.File {
+		name := strings.ToLower(f.Name)
+		if !strings.HasSuffix(name, ".csv") && !strings.HasSuffix(name, ".xml") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		return parse(content)
+	}
+	return nil, errors.New("no CSV or XML file in zip archive")
+}
+
+type xmlEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+func parseXml(data []byte) (map[string]map[document.Currency]float64, error) {
+	var env xmlEnvelope
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	rates := make(map[string]map[document.Currency]float64, len(env.Days))
+	for _, day := range env.Days {
+		dayRates := make(map[document.Currency]float64, len(day.Rates))
+		for _, r := range day.Rates {
+			v, err := strconv.ParseFloat(r.Rate, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rate %q for %s on %s: %w", r.Rate, r.Currency, day.Time, err)
+			}
+			dayRates[document.Currency(r.Currency)] = v
+		}
+		rates[day.Time] = dayRates
+	}
+	if len(rates) == 0 {
+		return nil, errors.New("no reference rates found")
+	}
+	return rates, nil
+}
+
+// parseCsv reads the "Date, USD, JPY, ...," layout. Currencies that were not
+// quoted on a day are "N/A".
+func parseCsv(data []byte) (map[string]map[document.Currency]float64, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 || !strings.EqualFold(strings.TrimSpace(records[0][0]), "date") {
+		return nil, errors.New("no reference rates found")
+	}
+
+	header := records[0]
+	rates := make(map[string]map[document.Currency]float64, len(records)-1)
+	for _, record := range records[1:] {
+		day := strings.TrimSpace(record[0])
+		if day == "" {
+			continue
+		}
+		// The daily file writes dates as "2 January 2006".
+		if d, err := time.Parse("2 January 2006", day); err == nil {
+			day = d.Format("2006-01-02")
+		}
+		dayRates := make(map[document.Currency]float64, len(record)-1)
+		for i := 1; i < len(record) && i < len(header); i++ {
+			c, v := strings.TrimSpace(header[i]), strings.TrimSpace(record[i])
+			if c == "" || v == "" || v == "N/A" {
+				continue
+			}
+			rate, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rate %q for %s on %s: %w", v, c, day, err)
+			}
+			dayRates[document.Currency(c)] = rate
+		}
+		rates[day] = dayRates
+	}
+	return rates, nil
+}
@@ -0,0 +1,156 @@
+package ecbtest
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/converter"
+	"github.com/lazeratops/optimusdime/src/converter/ecb"
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/stretchr/testify/require"
+)
+
+const histXml = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<gesmes:Sender>
+		<gesmes:name>European Central Bank</gesmes:name>
+	</gesmes:Sender>
+	<Cube>
+		<Cube time="2025-01-03">
+			<Cube currency="USD" rate="1.0299"/>
+			<Cube currency="SEK" rate="11.4925"/>
+		</Cube>
+		<Cube time="2025-01-02">
+			<Cube currency="USD" rate="1.0321"/>
+			<Cube currency="SEK" rate="11.5"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+const histCsv = `Date,USD,SEK,ISK,
+2025-01-03,1.0299,11.4925,N/A,
+2025-01-02,1.0321,11.5,N/A,
+`
+
+const dailyCsv = `Date, USD, SEK, 
+03 January 2025, 1.0299, 11.4925, 
+`
+
+func writeZip(t *testing.T, name string, content string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create(name)
+	require.NoError(t, err)
+	_, err = f.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestRate(t *testing.T) {
+	t.Parallel()
+	jan3 := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name string
+		file string
+		data []byte
+	}{
+		{name: "xml", file: "eurofxref-hist.xml", data: []byte(histXml)},
+		{name: "csv", file: "eurofxref-hist.csv", data: []byte(histCsv)},
+		{name: "daily csv", file: "eurofxref.csv", data: []byte(dailyCsv)},
+		{name: "zip", file: "eurofxref-hist.zip", data: writeZip(t, "eurofxref-hist.csv", histCsv)},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), tc.file)
+			require.NoError(t, os.WriteFile(path, tc.data, 0o600))
+
+			api, err := ecb.NewEcb(path)
+			require.NoError(t, err)
+
+			rate, err := api.Rate(context.Background(), document.EUR, document.SEK, jan3)
+			require.NoError(t, err)
+			require.Equal(t, converter.Rate{
+				From:     document.EUR,
+				To:       document.SEK,
+				Value:    11.4925,
+				Date:     jan3,
+				Provider: "ecb",
+			}, rate)
+
+			rate, err = api.Rate(context.Background(), document.SEK, document.USD, jan3)
+			require.NoError(t, err)
+			require.InDelta(t, 1.0299/11.4925, rate.Value, 1e-12)
+
+			rate, err = api.Rate(context.Background(), document.USD, document.EUR, jan3)
+			require.NoError(t, err)
+			require.InDelta(t, 1/1.0299, rate.Value, 1e-12)
+
+			_, err = api.Rate(context.Background(), document.Currency("ISK"), document.EUR, jan3)
+			require.ErrorIs(t, err, converter.ErrNoRate)
+
+			// No reference rates are published at weekends.
+			_, err = api.Rate(context.Background(), document.SEK, document.EUR, time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC))
+			require.ErrorIs(t, err, converter.ErrNoRate)
+		})
+	}
+}
+
+func TestConvertFromUrl(t *testing.T) {
+	t.Parallel()
+	requests := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, err := w.Write([]byte(histXml))
+		require.NoError(t, err)
+	}))
+	defer testServer.Close()
+
+	api, err := ecb.NewEcb(testServer.URL + "/eurofxref-hist.xml")
+	require.NoError(t, err)
+
+	jan2 := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	gotDoc, failedDoc, err := api.Convert(context.Background(), document.EUR, &document.Document{
+		Transactions: []document.Transaction{
+			{Description: "a", Date: jan2, Amount: document.MustParseMoney("115.00"), Currency: document.SEK},
+			{Description: "b", Date: jan2, Amount: document.MustParseMoney("10.32"), Currency: document.USD},
+		},
+	})
+	require.NoError(t, err)
+	require.Empty(t, failedDoc.Transactions)
+	require.Equal(t, "10.00", gotDoc.Transactions[0].Amount.String())
+	require.Equal(t, "10.00", gotDoc.Transactions[1].Amount.String())
+	require.Equal(t, "ecb", gotDoc.Transactions[0].Conversion.RateProvider)
+	require.Equal(t, 1, requests)
+}
+
+func TestBadStatusCode(t *testing.T) {
+	t.Parallel()
+	var requests atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer testServer.Close()
+
+	api, err := ecb.NewEcb(testServer.URL)
+	require.NoError(t, err)
+	// The failed download is not repeated for every lookup.
+	for range 3 {
+		_, err = api.Rate(context.Background(), document.SEK, document.EUR, time.Now())
+		require.ErrorIs(t, err, converter.ErrFailedAPICall)
+	}
+	require.Equal(t, int32(1), requests.Load())
+}
@@ -2,4 +2,15 @@ package converter
 
 import "errors"
 
-var ErrFailedAPICall = errors.New("bad response from currency exchange API")
+var (
+	ErrFailedAPICall = errors.New("bad response from currency exchange API")
+	ErrNoRate        = errors.New("no exchange rate")
+	// ErrNotFound marks a 404 response. Providers whose APIs answer 404
+	// for dates without rates turn it into ErrNoRate.
+	ErrNotFound = errors.New("not found")
+	// ErrFatal marks errors after which a provider cannot answer any
+	// lookup, such as a rejected API key or an exhausted quota.
+	// RateConverter stops at the first one instead of failing every
+	// transaction in turn.
+	ErrFatal = errors.New("rate provider unusable")
+)
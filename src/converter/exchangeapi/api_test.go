@@ -37,3 +37,54 @@ func TestGetUrl(t *testing.T) {
 		})
 	}
 }
+
+func TestGetUrls(t *testing.T) {
+	t.Parallel()
+	date := time.Date(2020, 11, 24, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		mirrors  []string
+		version  Version
+		wantUrls []string
+	}{
+		{
+			name: "default mirrors",
+			wantUrls: []string{
+				"https://2020-11-24.currency-api.pages.dev/v1/currencies/sek.json",
+				"https://cdn.jsdelivr.net/npm/@fawazahmed0/currency-api@2020-11-24/v1/currencies/sek.json",
+			},
+		},
+		{
+			name:    "latest",
+			version: VersionLatest,
+			wantUrls: []string{
+				"https://latest.currency-api.pages.dev/v1/currencies/sek.json",
+				"https://cdn.jsdelivr.net/npm/@fawazahmed0/currency-api@latest/v1/currencies/sek.json",
+			},
+		},
+		{
+			name:     "pinned release",
+			mirrors:  []string{"http://localhost:8080/{version}/{date}/{currency}.json"},
+			version:  Version("2024-03-06"),
+			wantUrls: []string{"http://localhost:8080/2024-03-06/2020-11-24/sek.json"},
+		},
+		{
+			name:     "base url",
+			mirrors:  []string{"http://localhost:8080/rates/"},
+			wantUrls: []string{"http://localhost:8080/rates/sek.json"},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			api, err := NewExchangeApi("")
+			require.NoError(t, err)
+			if tc.mirrors != nil {
+				require.NoError(t, api.SetMirrors(tc.mirrors...))
+			}
+			api.SetVersion(tc.version)
+			require.Equal(t, tc.wantUrls, api.getUrls(date, document.SEK))
+		})
+	}
+}
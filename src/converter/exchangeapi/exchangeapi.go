@@ -1,11 +1,10 @@
 package exchangeapi
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"strings"
@@ -16,144 +15,246 @@ import (
 )
 
 const (
-	//exchangeApiUrl         = "https://cdn.jsdelivr.net/npm/@fawazahmed0/currency-api@latest/v1/"
 	defaultApiUrl = "currency-api.pages.dev/v1/currencies"
+	providerName  = "exchangeapi"
 )
 
+// DefaultMirrors are the API's Cloudflare and jsDelivr deployments, tried
+// in this order.
+var DefaultMirrors = []string{
+	"https://{version}." + defaultApiUrl + "/{currency}.json",
+	"https://cdn.jsdelivr.net/npm/@fawazahmed0/currency-api@{version}/v1/currencies/{currency}.json",
+}
+
+// Version selects the release rates are read from. Besides VersionDate and
+// VersionLatest, it can be a release date (YYYY-MM-DD) to read every rate
+// from that one release.
+type Version string
+
+const (
+	// VersionDate reads the rates of each date from that date's release.
+	VersionDate Version = ""
+	// VersionLatest reads every rate from the latest release.
+	VersionLatest Version = "latest"
+)
+
+// ParseVersion reads "date", "latest" or a release date.
+func ParseVersion(s string) (Version, error) {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "", "date":
+		return VersionDate, nil
+	case "latest":
+		return VersionLatest, nil
+	}
+	if _, err := time.Parse("2006-01-02", s); err != nil {
+		return "", fmt.Errorf("invalid exchangeapi version %q (want date, latest or a release date YYYY-MM-DD)", s)
+	}
+	return Version(s), nil
+}
+
 type Api struct {
-	url    string
-	schema string
+	// mirrors are URL templates, see SetMirrors.
+	mirrors  []string
+	version  Version
+	rounding document.RoundingMode
+	client   *http.Client
+	retry    converter.RetryPolicy
+
+	responses converter.Memo[string, *ApiResponse]
 }
 
+// NewExchangeApi uses DefaultMirrors when apiUrl is empty, and otherwise
+// only apiUrl, as for SetMirrors.
 func NewExchangeApi(apiUrl string) (*Api, error) {
-	var schema string
+	api := &Api{
+		retry: converter.DefaultRetryPolicy,
+	}
 	if apiUrl == "" {
-		apiUrl = defaultApiUrl
-		schema = "https"
-	} else {
+		return api, api.SetMirrors(DefaultMirrors...)
+	}
+	return api, api.SetMirrors(apiUrl)
+}
 
-		// Parse URL
-		parsedURL, err := url.Parse(apiUrl)
+// SetMirrors sets the URLs to fetch rates from, tried in order until one
+// answers. A URL may contain {currency}, {date} and {version}, which are
+// replaced by the lower-case target currency, the date asked for and the
+// release of SetVersion. A URL without {currency} is a base URL that
+// serves {currency}.json files.
+func (api *Api) SetMirrors(urls ...string) error {
+	if len(urls) == 0 {
+		return errors.New("no exchangeapi mirrors given")
+	}
+	mirrors := make([]string, 0, len(urls))
+	for _, u := range urls {
+		u = strings.TrimSpace(u)
+		if !strings.Contains(u, "{currency}") {
+			u = strings.TrimSuffix(u, "/") + "/{currency}.json"
+		}
+		// Placeholders are not valid in a host, so check a filled-in URL.
+		parsed, err := url.Parse(fill(u, time.Time{}, "", "latest"))
 		if err != nil {
-			return nil, fmt.Errorf("invalid API URL: %w", err)
+			return fmt.Errorf("invalid API URL: %w", err)
 		}
-		if parsedURL.Scheme != "" {
-			schema = parsedURL.Scheme
+		if parsed.Scheme != "http" && parsed.Scheme != "https" {
+			return fmt.Errorf("invalid API URL %q: want an http(s) URL", u)
 		}
-		apiUrl = parsedURL.Host
+		mirrors = append(mirrors, u)
 	}
+	api.mirrors = mirrors
+	return nil
+}
 
-	return &Api{
-		url:    apiUrl,
-		schema: schema,
-	}, nil
+// SetVersion sets the release to read rates from. The default is
+// VersionDate.
+func (api *Api) SetVersion(version Version) {
+	api.version = version
 }
 
-func (api *Api) getUrl(date time.Time, targetCurrency document.Currency) string {
-	dateStr := date.Format("2006-01-02")
+// getUrls returns the URL of every mirror for the rates against
+// targetCurrency on date.
+func (api *Api) getUrls(date time.Time, targetCurrency document.Currency) []string {
 	c := string(targetCurrency)
 	if c == "" {
-		return ""
+		return nil
+	}
+	version := string(api.version)
+	if api.version == VersionDate {
+		version = date.Format("2006-01-02")
 	}
-	lower := strings.ToLower(c)
-	if api.url != defaultApiUrl {
-		return fmt.Sprintf("%s://%s/%s.json", api.schema, api.url, lower)
+	urls := make([]string, len(api.mirrors))
+	for i, m := range api.mirrors {
+		urls[i] = fill(m, date, strings.ToLower(c), version)
 	}
-	return fmt.Sprintf("%s://%s.%s/%s.json", api.schema, dateStr, api.url, lower)
+	return urls
 }
 
-func (api *Api) Convert(targetCurrency document.Currency, statement *document.Document) (*document.Document, *document.Document, error) {
-	if len(statement.Transactions) == 0 {
-		return nil, nil, errors.New("no transactions to convert")
+// getUrl returns the URL of the first mirror.
+func (api *Api) getUrl(date time.Time, targetCurrency document.Currency) string {
+	urls := api.getUrls(date, targetCurrency)
+	if len(urls) == 0 {
+		return ""
 	}
+	return urls[0]
+}
 
-	newDoc := document.Document{
-		Transactions: []document.Transaction{},
-	}
+func fill(template string, date time.Time, currency string, version string) string {
+	return strings.NewReplacer(
+		"{currency}", currency,
+		"{date}", date.Format("2006-01-02"),
+		"{version}", version,
+	).Replace(template)
+}
+
+// SetRounding sets how converted amounts are rounded to the minor units of
+// the target currency. The default is document.HalfEven.
+func (api *Api) SetRounding(mode document.RoundingMode) {
+	api.rounding = mode
+}
 
-	failedToConvertDoc := document.Document{
-		Transactions: []document.Transaction{},
+// SetHttpClient sets the client for API requests. The default is
+// converter.DefaultHttpClient.
+func (api *Api) SetHttpClient(client *http.Client) {
+	api.client = client
+}
+
+// SetRetryPolicy sets how failed API requests are retried. The default is
+// converter.DefaultRetryPolicy.
+func (api *Api) SetRetryPolicy(policy converter.RetryPolicy) {
+	api.retry = policy
+}
+
+// Convert converts statement with exchangeapi rates.
+func (api *Api) Convert(ctx context.Context, targetCurrency document.Currency, statement *document.Document) (*document.Document, *document.Document, error) {
+	c := converter.NewRateConverter(api)
+	c.SetRounding(api.rounding)
+	return c.Convert(ctx, targetCurrency, statement)
+}
+
+// Name includes the version unless it is VersionDate, such as
+// "exchangeapi@latest", so that rates of one release are not cached as the
+// rates of each date asked for.
+func (api *Api) Name() string {
+	if api.version == VersionDate {
+		return providerName
 	}
+	return providerName + "@" + string(api.version)
+}
 
-	sourceCurrenciesForDate := make(map[time.Time][]document.Transaction)
+// Rate returns the rate from one currency to another on date. The API
+// quotes every currency against the target, so one response is fetched
+// per date and target and reused for every source currency.
+func (api *Api) Rate(ctx context.Context, from document.Currency, to document.Currency, date time.Time) (converter.Rate, error) {
+	res, err := api.response(ctx, date, to)
+	if err != nil {
+		return converter.Rate{}, err
+	}
 
-	for _, oldTransaction := range statement.Transactions {
-		sourceCurrenciesForDate[oldTransaction.Date] = append(sourceCurrenciesForDate[oldTransaction.Date], oldTransaction)
+	rates, ok := res.Rates[strings.ToLower(string(to))]
+	if !ok {
+		return converter.Rate{}, fmt.Errorf("no rates against %s: %w", to, converter.ErrNoRate)
+	}
+	rate, ok := rates[strings.ToLower(string(from))]
+	if !ok || rate == 0 {
+		return converter.Rate{}, fmt.Errorf("no rate for %s to %s: %w", from, to, converter.ErrNoRate)
 	}
 
-	var lastError error
-	for date, transactions := range sourceCurrenciesForDate {
-		c := document.Currency(targetCurrency)
-		url := api.getUrl(date, c)
-		if url == "" {
-			return nil, nil, fmt.Errorf("failed to get api URL for date %v and currency %v", date, c)
-		}
-		resBody, err := api.fetch(url)
-		if err != nil {
-			failedToConvertDoc.Transactions = append(failedToConvertDoc.Transactions, transactions...)
-			log.Printf("\n Failed to fetch transactions from URL: %s: %v", url, err)
-			lastError = err
-			continue
-		}
+	// The API serves its latest rates for dates it has no release for,
+	// so record the date it answered with.
+	rateDate := date
+	if d, err := time.Parse("2006-01-02", res.Date); err == nil {
+		rateDate = d
+	}
 
-		var currencyRes ApiResponse
-		if err := json.Unmarshal(resBody, &currencyRes); err != nil {
-			failedToConvertDoc.Transactions = append(failedToConvertDoc.Transactions, transactions...)
-			log.Printf("\n Failed to parse currency response from URL: %s: %v", url, err)
-			lastError = err
-			continue
-		}
+	return converter.Rate{
+		From:     from,
+		To:       to,
+		Value:    1 / rate,
+		Date:     rateDate,
+		Provider: api.Name(),
+	}, nil
+}
 
-		sTargetCurrency := strings.ToLower(string(targetCurrency))
-		rates, ok := currencyRes.Rates[strings.ToLower(sTargetCurrency)]
-		if !ok {
-			failedToConvertDoc.Transactions = append(failedToConvertDoc.Transactions, transactions...)
-			log.Printf("\n Failed to get currency rates for target currenct from URL: %s: %v", url, err)
-			lastError = err
-			continue
-		}
+// response fetches the rates against targetCurrency from the first mirror
+// that answers.
+func (api *Api) response(ctx context.Context, date time.Time, targetCurrency document.Currency) (*ApiResponse, error) {
+	urls := api.getUrls(date, targetCurrency)
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("failed to get api URL for date %v and currency %v", date, targetCurrency)
+	}
 
-		for _, oldTransaction := range transactions {
-			sSourceCurrency := strings.ToLower(string(oldTransaction.Currency))
-			rate, ok := rates[sSourceCurrency]
-			if !ok {
-				failedToConvertDoc.Transactions = append(failedToConvertDoc.Transactions, oldTransaction)
-				log.Printf("\n Failed to get currency rates for transaction %s: %v", oldTransaction.Description, err)
-				lastError = err
-				continue
+	return api.responses.Do(strings.Join(urls, " "), func() (*ApiResponse, error) {
+		var errs []error
+		notFound := 0
+		for _, url := range urls {
+			res, err := api.fetch(ctx, url)
+			if err == nil {
+				return res, nil
 			}
-			convertedAmount := oldTransaction.Amount / rate
-
-			transaction := &document.Transaction{
-				Description: oldTransaction.Description,
-				Currency:    targetCurrency,
-				Date:        oldTransaction.Date,
-				Amount:      convertedAmount,
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			if errors.Is(err, converter.ErrNotFound) {
+				notFound++
 			}
-			newDoc.Transactions = append(newDoc.Transactions, *transaction)
+			errs = append(errs, err)
 		}
-	}
-	if len(newDoc.Transactions) == 0 && lastError != nil {
-		return nil, nil, lastError
-	}
-	return &newDoc, &failedToConvertDoc, nil
+		// Mirrors answer 404 for dates without a release.
+		if notFound == len(urls) {
+			errs = append(errs, fmt.Errorf("no %s release for %s: %w", providerName, date.Format("2006-01-02"), converter.ErrNoRate))
+		}
+		return nil, errors.Join(errs...)
+	})
 }
 
-func (api *Api) fetch(url string) ([]byte, error) {
-	resp, err := http.Get(url)
+func (api *Api) fetch(ctx context.Context, url string) (*ApiResponse, error) {
+	resBody, err := converter.Fetch(ctx, api.client, api.retry, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch data: %w", err)
+		return nil, fmt.Errorf("failed to fetch rates from URL %s: %w", url, err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+	var currencyRes ApiResponse
+	if err := json.Unmarshal(resBody, &currencyRes); err != nil {
+		return nil, fmt.Errorf("failed to parse currency response from URL %s: %w", url, err)
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("api returned error %d: %s: %w", resp.StatusCode, resp.Status, converter.ErrFailedAPICall)
-	}
-
-	return body, nil
+	return &currencyRes, nil
 }
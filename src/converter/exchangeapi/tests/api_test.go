@@ -1,6 +1,7 @@
 package apitest
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -39,7 +40,7 @@ func TestConvert(t *testing.T) {
 					{
 						Description: "transaction1",
 						Currency:    document.SEK,
-						Amount:      100,
+						Amount:      document.MustParseMoney("100.00"),
 						Date:        time.Now(),
 					},
 				},
@@ -63,7 +64,7 @@ func TestConvert(t *testing.T) {
 					{
 						Description: "transaction1",
 						Currency:    document.SEK,
-						Amount:      1124.233239,
+						Amount:      document.MustParseMoney("1124.23"),
 						Date:        date_20250101,
 					},
 				},
@@ -74,8 +75,15 @@ func TestConvert(t *testing.T) {
 					{
 						Description: "transaction1",
 						Currency:    document.EUR,
-						Amount:      100,
+						Amount:      document.MustParseMoney("100.00"),
 						Date:        date_20250101,
+						Conversion: &document.Conversion{
+							OriginalAmount:   document.MustParseMoney("1124.23"),
+							OriginalCurrency: document.SEK,
+							Rate:             1 / 11.24233239,
+							RateDate:         date_20250101,
+							RateProvider:     "exchangeapi",
+						},
 					},
 				},
 			},
@@ -97,7 +105,7 @@ func TestConvert(t *testing.T) {
 			api, err := exchangeapi.NewExchangeApi(testServer.URL)
 			require.NoError(t, err)
 
-			gotDoc, _, gotErr := api.Convert(tc.targetCurrency, tc.sourceDocument)
+			gotDoc, _, gotErr := api.Convert(context.Background(), tc.targetCurrency, tc.sourceDocument)
 			require.ErrorIs(t, gotErr, tc.wantErr)
 			if gotErr == nil {
 				require.EqualValues(t, tc.wantDocument, gotDoc)
@@ -105,3 +113,63 @@ func TestConvert(t *testing.T) {
 		})
 	}
 }
+
+func TestMirrors(t *testing.T) {
+	t.Parallel()
+	date := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer down.Close()
+	var paths []string
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		_, err := w.Write([]byte(`{"date": "2024-12-31", "eur": {"sek": 11.5}}`))
+		require.NoError(t, err)
+	}))
+	defer up.Close()
+
+	api, err := exchangeapi.NewExchangeApi("")
+	require.NoError(t, err)
+	require.NoError(t, api.SetMirrors(down.URL+"/{currency}.json", up.URL+"/currency-api@{version}/{currency}.json"))
+	api.SetVersion(exchangeapi.VersionLatest)
+
+	rate, err := api.Rate(context.Background(), document.SEK, document.EUR, date)
+	require.NoError(t, err)
+	require.Equal(t, 1/11.5, rate.Value)
+	// The latest release may be from another day than the one asked for.
+	require.Equal(t, time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), rate.Date)
+	require.Equal(t, []string{"/currency-api@latest/eur.json"}, paths)
+	// Caches key rates by provider name, so a release other than the
+	// date's own has its own name.
+	require.Equal(t, "exchangeapi@latest", rate.Provider)
+	require.Equal(t, "exchangeapi@latest", api.Name())
+	api.SetVersion(exchangeapi.VersionDate)
+	require.Equal(t, "exchangeapi", api.Name())
+
+	// A date no mirror has a release for has no rate, so a fallback can
+	// run.
+	require.NoError(t, api.SetMirrors(down.URL+"/{date}/{currency}.json", down.URL+"/{version}/{currency}.json"))
+	_, err = api.Rate(context.Background(), document.SEK, document.EUR, date)
+	require.ErrorIs(t, err, converter.ErrNoRate)
+
+	require.Error(t, api.SetMirrors())
+	require.Error(t, api.SetMirrors("ftp://example.com/{currency}.json"))
+}
+
+func TestParseVersion(t *testing.T) {
+	t.Parallel()
+	for input, want := range map[string]exchangeapi.Version{
+		"":           exchangeapi.VersionDate,
+		"date":       exchangeapi.VersionDate,
+		"Latest":     exchangeapi.VersionLatest,
+		"2024-03-06": exchangeapi.Version("2024-03-06"),
+	} {
+		got, err := exchangeapi.ParseVersion(input)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+	_, err := exchangeapi.ParseVersion("yesterday")
+	require.Error(t, err)
+}
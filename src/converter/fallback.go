@@ -0,0 +1,84 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FallbackPolicy decides which other date's rate to use when a provider has
+// no rate for a transaction's date, as on weekends and bank holidays.
+type FallbackPolicy int
+
+const (
+	// FallbackFail leaves transactions without a rate unconverted.
+	FallbackFail FallbackPolicy = iota
+	// FallbackPrevious uses the closest earlier date with a rate.
+	FallbackPrevious
+	// FallbackNext uses the closest later date with a rate.
+	FallbackNext
+	// FallbackNearest uses the closest date either way, earlier on a tie.
+	FallbackNearest
+)
+
+// DefaultFallbackDays is how far a fallback looks by default. It covers
+// a weekend next to a bank holiday or two.
+const DefaultFallbackDays = 5
+
+func (p FallbackPolicy) String() string {
+	switch p {
+	case FallbackFail:
+		return "fail"
+	case FallbackPrevious:
+		return "previous"
+	case FallbackNext:
+		return "next"
+	case FallbackNearest:
+		return "nearest"
+	}
+	return fmt.Sprintf("FallbackPolicy(%d)", int(p))
+}
+
+func ParseFallbackPolicy(s string) (FallbackPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "fail", "none":
+		return FallbackFail, nil
+	case "previous", "prev":
+		return FallbackPrevious, nil
+	case "next":
+		return FallbackNext, nil
+	case "nearest":
+		return FallbackNearest, nil
+	}
+	return 0, fmt.Errorf("unknown fallback policy %q (want previous, next, nearest or fail)", s)
+}
+
+// offsets returns the day offsets to try after the date itself, in order.
+func (p FallbackPolicy) offsets(maxDays int) []int {
+	var offsets []int
+	for d := 1; d <= maxDays; d++ {
+		switch p {
+		case FallbackPrevious:
+			offsets = append(offsets, -d)
+		case FallbackNext:
+			offsets = append(offsets, d)
+		case FallbackNearest:
+			offsets = append(offsets, -d, d)
+		}
+	}
+	return offsets
+}
+
+// span widens start and end by the dates a fallback may look at.
+func (p FallbackPolicy) span(start time.Time, end time.Time, maxDays int) (time.Time, time.Time) {
+	first, last := start, end
+	for _, offset := range p.offsets(maxDays) {
+		if d := start.AddDate(0, 0, offset); d.Before(first) {
+			first = d
+		}
+		if d := end.AddDate(0, 0, offset); d.After(last) {
+			last = d
+		}
+	}
+	return first, last
+}
@@ -0,0 +1,130 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultHttpClient is used by providers without an injected client. Its
+// timeout keeps a hung endpoint from hanging a conversion.
+var DefaultHttpClient = &http.Client{
+	Timeout: 30 * time.Second,
+}
+
+// RetryPolicy decides how often and how long to wait before retrying a
+// request that got a 429 or 5xx response.
+type RetryPolicy struct {
+	// MaxAttempts counts the first request. 1 means no retries.
+	MaxAttempts int
+	// BaseDelay is doubled after every attempt, up to MaxDelay, and
+	// jittered by up to half either way.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// delay returns how long to wait before the given retry, 1-based. A
+// Retry-After header, in seconds or as a date, takes precedence but is
+// capped at MaxDelay.
+func (p RetryPolicy) delay(retry int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(d, p.MaxDelay)
+		}
+	}
+	d := p.BaseDelay << (retry - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	// Jitter by ±50% so that concurrent lookups do not retry in step.
+	return d/2 + rand.N(d+1)
+}
+
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// Fetch GETs url and returns the body of a 200 response. Network errors
+// and responses with a 429 or 5xx status are retried as policy allows;
+// other statuses fail with ErrFailedAPICall straight away, and 404s with
+// ErrNotFound too.
+func Fetch(ctx context.Context, client *http.Client, policy RetryPolicy, url string) ([]byte, error) {
+	if client == nil {
+		client = DefaultHttpClient
+	}
+	attempts := max(policy.MaxAttempts, 1)
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			// Connection resets, timeouts and DNS failures are often
+			// brief, but a done context is not.
+			fetchErr := fmt.Errorf("failed to fetch data: %w", err)
+			if ctx.Err() != nil || attempt >= attempts {
+				return nil, fetchErr
+			}
+			if err := wait(ctx, policy.delay(attempt, nil)); err != nil {
+				return nil, fmt.Errorf("%w (giving up after %v)", fetchErr, err)
+			}
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			return body, nil
+		}
+		statusErr := fmt.Errorf("api returned error %d: %s: %w", resp.StatusCode, resp.Status, ErrFailedAPICall)
+		if resp.StatusCode == http.StatusNotFound {
+			statusErr = fmt.Errorf("%w (%w)", statusErr, ErrNotFound)
+		}
+		if !retryable(resp.StatusCode) || attempt >= attempts {
+			return nil, statusErr
+		}
+		if err := wait(ctx, policy.delay(attempt, resp)); err != nil {
+			return nil, fmt.Errorf("%w (giving up after %v)", statusErr, err)
+		}
+	}
+}
+
+// wait sleeps for d, or returns the cause when ctx is done first.
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package converter
+
+import "sync"
+
+// Memo runs a function once per key and keeps its result, so that
+// providers fetch each response once even when rates are looked up
+// concurrently. Errors are shared with callers waiting at the time but not
+// kept, so a later call tries again.
+type Memo[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*memoCall[V]
+}
+
+type memoCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+func (m *Memo[K, V]) Do(key K, fn func() (V, error)) (V, error) {
+	m.mu.Lock()
+	if m.calls == nil {
+		m.calls = make(map[K]*memoCall[V])
+	}
+	if c, ok := m.calls[key]; ok {
+		m.mu.Unlock()
+		<-c.done
+		return c.value, c.err
+	}
+	c := &memoCall[V]{
+		done: make(chan struct{}),
+	}
+	m.calls[key] = c
+	m.mu.Unlock()
+
+	c.value, c.err = fn()
+	if c.err != nil {
+		m.mu.Lock()
+		delete(m.calls, key)
+		m.mu.Unlock()
+	}
+	close(c.done)
+	return c.value, c.err
+}
+
+// Store keeps value for key as if a call had returned it, unless the key
+// already has a call. Providers use it to keep the rates of a range
+// fetched in one request.
+func (m *Memo[K, V]) Store(key K, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.calls == nil {
+		m.calls = make(map[K]*memoCall[V])
+	}
+	if _, ok := m.calls[key]; ok {
+		return
+	}
+	c := &memoCall[V]{
+		done:  make(chan struct{}),
+		value: value,
+	}
+	close(c.done)
+	m.calls[key] = c
+}
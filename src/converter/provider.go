@@ -0,0 +1,41 @@
+package converter
+
+import (
+	"context"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/document"
+)
+
+// Rate is the value of one unit of From in units of To.
+type Rate struct {
+	From  document.Currency
+	To    document.Currency
+	Value float64
+	// Date is the date the rate applies to. Providers that fall back to an
+	// earlier release set it to that release's date.
+	Date     time.Time
+	Provider string
+}
+
+// RateProvider answers what one currency was worth in another on a date.
+// Converting documents is left to RateConverter.
+type RateProvider interface {
+	// Name identifies the provider in conversion provenance.
+	Name() string
+	// Rate returns the rate from one currency to another on date. It
+	// returns an error wrapping ErrNoRate when the provider has no rate
+	// for the pair on that date.
+	Rate(ctx context.Context, from document.Currency, to document.Currency, date time.Time) (Rate, error)
+}
+
+// RangeProvider is a RateProvider that can fetch the rates of a whole date
+// range in a single request. RateConverter prefetches the date span of a
+// statement before looking up rates, which Rate then answers from memory.
+type RangeProvider interface {
+	RateProvider
+	// Prefetch loads the rates from one currency to another from start to
+	// end, inclusive. It is only an optimisation: dates it did not load
+	// are still fetched by Rate.
+	Prefetch(ctx context.Context, from document.Currency, to document.Currency, start time.Time, end time.Time) error
+}
@@ -0,0 +1,71 @@
+package converter
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimited is an http.RoundTripper that spaces out the requests sent
+// through it, to stay within an API's request limits. Rates a provider
+// answers from memory or a cache send no request, so they do not wait.
+type RateLimited struct {
+	transport http.RoundTripper
+	interval  time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewRateLimited allows at most perSecond requests a second through
+// transport, or http.DefaultTransport when it is nil.
+func NewRateLimited(transport http.RoundTripper, perSecond float64) *RateLimited {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &RateLimited{
+		transport: transport,
+		interval:  time.Duration(float64(time.Second) / perSecond),
+	}
+}
+
+// NewRateLimitedClient returns a copy of client, or of DefaultHttpClient
+// when it is nil, that sends at most perSecond requests a second.
+func NewRateLimitedClient(client *http.Client, perSecond float64) *http.Client {
+	if client == nil {
+		client = DefaultHttpClient
+	}
+	limited := *client
+	limited.Transport = NewRateLimited(client.Transport, perSecond)
+	return &limited
+}
+
+// RoundTrip waits for its turn, so retries count against the limit too.
+func (r *RateLimited) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := r.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return r.transport.RoundTrip(req)
+}
+
+// wait blocks until the next request is allowed.
+func (r *RateLimited) wait(ctx context.Context) error {
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	wait := r.next.Sub(now)
+	r.next = r.next.Add(r.interval)
+	r.mu.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+	}
+	return nil
+}
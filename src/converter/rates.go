@@ -0,0 +1,248 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/document"
+)
+
+// RateConverter converts documents with the rates of a RateProvider.
+type RateConverter struct {
+	provider     RateProvider
+	rounding     document.RoundingMode
+	fallback     FallbackPolicy
+	fallbackDays int
+	parallelism  int
+}
+
+// DefaultParallelism is how many rates are looked up at once by default.
+const DefaultParallelism = 4
+
+func NewRateConverter(provider RateProvider) *RateConverter {
+	return &RateConverter{
+		provider:    provider,
+		parallelism: DefaultParallelism,
+	}
+}
+
+// SetRounding sets how converted amounts are rounded to the minor units of
+// the target currency. The default is document.HalfEven.
+func (c *RateConverter) SetRounding(mode document.RoundingMode) {
+	c.rounding = mode
+}
+
+// SetFallback sets which date's rate to use, at most maxDays away, when
+// the provider has no rate for a transaction's date. The default is
+// FallbackFail. The date used ends up in the transaction's Conversion.
+func (c *RateConverter) SetFallback(policy FallbackPolicy, maxDays int) {
+	c.fallback = policy
+	c.fallbackDays = maxDays
+}
+
+// SetParallelism sets how many rates are looked up at once. Values below 1
+// mean one at a time.
+func (c *RateConverter) SetParallelism(n int) {
+	c.parallelism = max(n, 1)
+}
+
+// Convert converts every transaction to targetCurrency and returns the
+// converted transactions and the ones that could not be converted, each in
+// statement order. It only returns an error when nothing was converted, or
+// when a lookup failed with ErrFatal.
+func (c *RateConverter) Convert(ctx context.Context, targetCurrency document.Currency, statement *document.Document) (*document.Document, *document.Document, error) {
+	if len(statement.Transactions) == 0 {
+		return nil, nil, errors.New("no transactions to convert")
+	}
+
+	newDoc := document.Document{
+		Transactions: []document.Transaction{},
+	}
+
+	failedToConvertDoc := document.Document{
+		Transactions: []document.Transaction{},
+	}
+
+	rates, err := c.rates(ctx, targetCurrency, statement.Transactions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var lastError error
+	for _, oldTransaction := range statement.Transactions {
+		res := rates[rateKey{
+			from: oldTransaction.Currency,
+			date: oldTransaction.Date,
+		}]
+		if res.err != nil {
+			failedToConvertDoc.Transactions = append(failedToConvertDoc.Transactions, oldTransaction)
+			log.Printf("\n Failed to get %s rate for %s to %s on %s: %v", c.provider.Name(), oldTransaction.Currency, targetCurrency, oldTransaction.Date.Format("2006-01-02"), res.err)
+			lastError = res.err
+			continue
+		}
+
+		convertedAmount, err := oldTransaction.Amount.Mul(res.rate.Value, targetCurrency.MinorUnits(), c.rounding)
+		if err != nil {
+			failedToConvertDoc.Transactions = append(failedToConvertDoc.Transactions, oldTransaction)
+			log.Printf("\n Failed to convert %s to %s: %v", oldTransaction.Amount, targetCurrency, err)
+			lastError = err
+			continue
+		}
+
+		transaction := oldTransaction
+		transaction.Currency = targetCurrency
+		transaction.Amount = convertedAmount
+		transaction.Conversion = &document.Conversion{
+			OriginalAmount:   oldTransaction.Amount,
+			OriginalCurrency: oldTransaction.Currency,
+			Rate:             res.rate.Value,
+			RateDate:         res.rate.Date,
+			RateProvider:     res.rate.Provider,
+		}
+		newDoc.Transactions = append(newDoc.Transactions, transaction)
+	}
+	if len(newDoc.Transactions) == 0 && lastError != nil {
+		return nil, &failedToConvertDoc, lastError
+	}
+	return &newDoc, &failedToConvertDoc, nil
+}
+
+type rateKey struct {
+	from document.Currency
+	date time.Time
+}
+
+type rateResult struct {
+	rate Rate
+	err  error
+}
+
+// rates looks up the rate of every pair and date in transactions, as
+// statements have many transactions per day and currency. Lookups run on
+// up to parallelism workers. The first ErrFatal error stops the lookups
+// and is returned, as is the context's error.
+func (c *RateConverter) rates(ctx context.Context, targetCurrency document.Currency, transactions []document.Transaction) (map[rateKey]rateResult, error) {
+	var keys []rateKey
+	seen := make(map[rateKey]bool)
+	for _, t := range transactions {
+		key := rateKey{
+			from: t.Currency,
+			date: t.Date,
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	if rp, ok := c.provider.(RangeProvider); ok {
+		c.prefetch(ctx, rp, targetCurrency, keys)
+	}
+
+	ctx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+
+	results := make([]rateResult, len(keys))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(c.parallelism, 1), len(keys)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				results[i].rate, results[i].err = c.rate(ctx, keys[i].from, targetCurrency, keys[i].date)
+				if errors.Is(results[i].err, ErrFatal) {
+					stop(results[i].err)
+				}
+			}
+		}()
+	}
+	for i := range keys {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+
+	rates := make(map[rateKey]rateResult, len(keys))
+	for i, key := range keys {
+		rates[key] = results[i]
+	}
+	return rates, nil
+}
+
+// prefetch asks a RangeProvider for the span of dates of each currency,
+// including the dates a fallback may need, so that the lookups that follow
+// do not fetch one date at a time. Failures are only logged, as the
+// lookups fetch whatever was not prefetched.
+func (c *RateConverter) prefetch(ctx context.Context, provider RangeProvider, targetCurrency document.Currency, keys []rateKey) {
+	type span struct {
+		start time.Time
+		end   time.Time
+	}
+	var currencies []document.Currency
+	spans := make(map[document.Currency]*span)
+	for _, key := range keys {
+		s, ok := spans[key.from]
+		if !ok {
+			spans[key.from] = &span{start: key.date, end: key.date}
+			currencies = append(currencies, key.from)
+			continue
+		}
+		if key.date.Before(s.start) {
+			s.start = key.date
+		}
+		if key.date.After(s.end) {
+			s.end = key.date
+		}
+	}
+
+	for _, from := range currencies {
+		if from == targetCurrency {
+			continue
+		}
+		start, end := c.fallback.span(spans[from].start, spans[from].end, c.fallbackDays)
+		if err := provider.Prefetch(ctx, from, targetCurrency, start, end); err != nil {
+			log.Printf("\n Failed to prefetch %s rates for %s to %s from %s to %s, fetching them by date: %v", provider.Name(), from, targetCurrency, start.Format("2006-01-02"), end.Format("2006-01-02"), err)
+		}
+	}
+}
+
+// rate asks the provider for the rate on date and, only when it has none
+// for that date, on the dates the fallback policy allows.
+func (c *RateConverter) rate(ctx context.Context, from document.Currency, to document.Currency, date time.Time) (Rate, error) {
+	rate, err := c.provider.Rate(ctx, from, to, date)
+	effective := date
+	if errors.Is(err, ErrNoRate) {
+		for _, offset := range c.fallback.offsets(c.fallbackDays) {
+			d := date.AddDate(0, 0, offset)
+			r, ferr := c.provider.Rate(ctx, from, to, d)
+			if ferr == nil {
+				rate, err, effective = r, nil, d
+				break
+			}
+			if !errors.Is(ferr, ErrNoRate) {
+				err = ferr
+				break
+			}
+		}
+	}
+	if err != nil {
+		return Rate{}, err
+	}
+	if rate.Date.IsZero() {
+		rate.Date = effective
+	}
+	if rate.Provider == "" {
+		rate.Provider = c.provider.Name()
+	}
+	return rate, nil
+}
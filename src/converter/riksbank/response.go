@@ -0,0 +1,34 @@
+package riksbank
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Series describes one SWEA series, e.g. "SEKEURPMI" with the mid
+// description "1 EUR".
+type Series struct {
+	SeriesId       string `json:"seriesId"`
+	ShortDesc      string `json:"shortDescription"`
+	MidDesc        string `json:"midDescription"`
+	LongDesc       string `json:"longDescription"`
+	ObservationMax string `json:"observationMaxDate"`
+	ObservationMin string `json:"observationMinDate"`
+}
+
+// units returns how many units of the currency the series is quoted per,
+// read from a mid description such as "100 JPY". It returns 0 when the
+// description does not say.
+func (s Series) units() int {
+	first, _, _ := strings.Cut(strings.TrimSpace(s.MidDesc), " ")
+	n, err := strconv.Atoi(first)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return n
+}
+
+type Observation struct {
+	Date  string   `json:"date"`
+	Value *float64 `json:"value"`
+}
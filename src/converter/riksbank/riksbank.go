@@ -0,0 +1,266 @@
+package riksbank
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/converter"
+	"github.com/lazeratops/optimusdime/src/document"
+)
+
+const (
+	defaultApiUrl = "https://api.riksbank.se/swea/v1"
+	providerName  = "riksbank"
+)
+
+// per100 lists the currencies the Riksbank quotes per 100 units, for when
+// the series metadata cannot be fetched.
+var per100 = map[document.Currency]bool{
+	"DKK": true,
+	"NOK": true,
+	"JPY": true,
+	"ISK": true,
+	"CZK": true,
+	"HUF": true,
+	"IDR": true,
+	"INR": true,
+	"KRW": true,
+	"THB": true,
+	"PHP": true,
+	"PKR": true,
+	"RUB": true,
+	"TWD": true,
+}
+
+// Api provides the Riksbank's daily mid rates from the SWEA API. Every
+// series gives SEK per unit (or per 100 units) of a currency, so rates
+// between other currencies are crossed through SEK. There are no rates on
+// Swedish bank holidays and weekends.
+type Api struct {
+	url      string
+	rounding document.RoundingMode
+	client   *http.Client
+	retry    converter.RetryPolicy
+
+	seriesMu sync.Mutex
+	// units is nil until the series list was fetched, and empty when that
+	// failed.
+	units map[document.Currency]int
+	// observations maps series and date to the rate, if there is one.
+	observations converter.Memo[string, *float64]
+}
+
+// NewRiksbank uses the SWEA API at apiUrl, or the Riksbank's when empty.
+func NewRiksbank(apiUrl string) (*Api, error) {
+	if apiUrl == "" {
+		apiUrl = defaultApiUrl
+	}
+	if !strings.HasPrefix(apiUrl, "http://") && !strings.HasPrefix(apiUrl, "https://") {
+		return nil, fmt.Errorf("invalid API URL %q: want an http(s) URL", apiUrl)
+	}
+	return &Api{
+		url:   strings.TrimSuffix(apiUrl, "/"),
+		retry: converter.DefaultRetryPolicy,
+	}, nil
+}
+
+// SetRounding sets how converted amounts are rounded to the minor units of
+// the target currency. The default is document.HalfEven.
+func (api *Api) SetRounding(mode document.RoundingMode) {
+	api.rounding = mode
+}
+
+// SetHttpClient sets the client for API requests. The default is
+// converter.DefaultHttpClient.
+func (api *Api) SetHttpClient(client *http.Client) {
+	api.client = client
+}
+
+// SetRetryPolicy sets how failed API requests are retried. The default is
+// converter.DefaultRetryPolicy.
+func (api *Api) SetRetryPolicy(policy converter.RetryPolicy) {
+	api.retry = policy
+}
+
+// Convert converts statement with Riksbank rates.
+func (api *Api) Convert(ctx context.Context, targetCurrency document.Currency, statement *document.Document) (*document.Document, *document.Document, error) {
+	c := converter.NewRateConverter(api)
+	c.SetRounding(api.rounding)
+	return c.Convert(ctx, targetCurrency, statement)
+}
+
+func (api *Api) Name() string {
+	return providerName
+}
+
+func (api *Api) Rate(ctx context.Context, from document.Currency, to document.Currency, date time.Time) (converter.Rate, error) {
+	fromSek, err := api.sekPerUnit(ctx, from, date)
+	if err != nil {
+		return converter.Rate{}, err
+	}
+	toSek, err := api.sekPerUnit(ctx, to, date)
+	if err != nil {
+		return converter.Rate{}, err
+	}
+	return converter.Rate{
+		From:     from,
+		To:       to,
+		Value:    fromSek / toSek,
+		Date:     date,
+		Provider: providerName,
+	}, nil
+}
+
+// sekPerUnit returns the SEK value of one unit of c on date.
+func (api *Api) sekPerUnit(ctx context.Context, c document.Currency, date time.Time) (float64, error) {
+	if c == document.SEK {
+		return 1, nil
+	}
+
+	units, err := api.seriesUnits(ctx, c)
+	if err != nil {
+		return 0, err
+	}
+
+	seriesId := fmt.Sprintf("SEK%sPMI", c)
+	day := date.Format("2006-01-02")
+	value, err := api.observations.Do(seriesId+"|"+day, func() (*float64, error) {
+		values, err := api.fetchObservations(ctx, seriesId, date, date)
+		if err != nil {
+			return nil, err
+		}
+		return values[day], nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if value == nil {
+		return 0, fmt.Errorf("no Riksbank rate for %s on %s: %w", c, day, converter.ErrNoRate)
+	}
+	return *value / float64(units), nil
+}
+
+// Prefetch fetches the observations of both currencies from start to end
+// with one request per series.
+func (api *Api) Prefetch(ctx context.Context, from document.Currency, to document.Currency, start time.Time, end time.Time) error {
+	for _, c := range []document.Currency{from, to} {
+		if c == document.SEK {
+			continue
+		}
+		if _, err := api.seriesUnits(ctx, c); err != nil {
+			return err
+		}
+
+		seriesId := fmt.Sprintf("SEK%sPMI", c)
+		values, err := api.fetchObservations(ctx, seriesId, start, end)
+		if err != nil {
+			return err
+		}
+		// Days after the last observation may still get one, so only the
+		// gaps before it are kept as days without a rate.
+		var last string
+		for day := range values {
+			last = max(last, day)
+		}
+		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+			day := d.Format("2006-01-02")
+			if day > last {
+				break
+			}
+			api.observations.Store(seriesId+"|"+day, values[day])
+		}
+	}
+	return nil
+}
+
+// fetchObservations returns the series' values by date from start to end.
+func (api *Api) fetchObservations(ctx context.Context, seriesId string, start time.Time, end time.Time) (map[string]*float64, error) {
+	url := fmt.Sprintf("%s/Observations/%s/%s/%s", api.url, seriesId, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	body, err := converter.Fetch(ctx, api.client, api.retry, url)
+	// The API answers 404 when the series has no observations in the range.
+	if errors.Is(err, converter.ErrNotFound) {
+		return nil, fmt.Errorf("no Riksbank rates in %s from %s to %s: %w", seriesId, start.Format("2006-01-02"), end.Format("2006-01-02"), converter.ErrNoRate)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rates from URL %s: %w", url, err)
+	}
+	var observations []Observation
+	if err := json.Unmarshal(body, &observations); err != nil {
+		return nil, fmt.Errorf("failed to parse observations from URL %s: %w", url, err)
+	}
+	values := make(map[string]*float64, len(observations))
+	for _, o := range observations {
+		if o.Value != nil {
+			values[o.Date] = o.Value
+		}
+	}
+	return values, nil
+}
+
+// seriesUnits returns how many units of c its series is quoted per. The
+// series list is fetched once; without it, per100 decides.
+func (api *Api) seriesUnits(ctx context.Context, c document.Currency) (int, error) {
+	api.seriesMu.Lock()
+	defer api.seriesMu.Unlock()
+	if api.units == nil {
+		units, err := api.fetchSeries(ctx)
+		if err != nil {
+			// A cancelled lookup says nothing about the API, so the next
+			// one tries again rather than guess the units for the run.
+			if ctx.Err() != nil {
+				return 0, err
+			}
+			log.Printf("\n Failed to fetch Riksbank series, assuming the usual quotation units: %v", err)
+			units = make(map[document.Currency]int)
+		}
+		api.units = units
+	}
+	if len(api.units) == 0 {
+		if per100[c] {
+			return 100, nil
+		}
+		return 1, nil
+	}
+	units, ok := api.units[c]
+	if !ok {
+		return 0, fmt.Errorf("the Riksbank does not quote %s: %w", c, converter.ErrNoRate)
+	}
+	return units, nil
+}
+
+func (api *Api) fetchSeries(ctx context.Context) (map[document.Currency]int, error) {
+	url := api.url + "/Series"
+	body, err := converter.Fetch(ctx, api.client, api.retry, url)
+	if err != nil {
+		return nil, err
+	}
+	var series []Series
+	if err := json.Unmarshal(body, &series); err != nil {
+		return nil, fmt.Errorf("failed to parse series from URL %s: %w", url, err)
+	}
+
+	units := make(map[document.Currency]int)
+	for _, s := range series {
+		// Exchange rate series are named SEK<code>PMI.
+		if len(s.SeriesId) != 9 || !strings.HasPrefix(s.SeriesId, "SEK") || !strings.HasSuffix(s.SeriesId, "PMI") {
+			continue
+		}
+		c := document.Currency(s.SeriesId[3:6])
+		n := s.units()
+		if n == 0 {
+			n = 1
+			if per100[c] {
+				n = 100
+			}
+		}
+		units[c] = n
+	}
+	return units, nil
+}
@@ -0,0 +1,212 @@
+package riksbanktest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/converter"
+	"github.com/lazeratops/optimusdime/src/converter/riksbank"
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/stretchr/testify/require"
+)
+
+const seriesBody = `[
+	{"seriesId": "SEKEURPMI", "shortDescription": "EUR", "midDescription": "1 EUR", "longDescription": "Euro"},
+	{"seriesId": "SEKUSDPMI", "shortDescription": "USD", "midDescription": "1 USD", "longDescription": "US dollar"},
+	{"seriesId": "SEKJPYPMI", "shortDescription": "JPY", "midDescription": "100 JPY", "longDescription": "Japanese yen"},
+	{"seriesId": "SEDP1MSTIBORDELAYC", "shortDescription": "STIBOR 1M", "midDescription": "STIBOR 1 month"}
+]`
+
+var observations = map[string]float64{
+	"SEKEURPMI": 11.4925,
+	"SEKUSDPMI": 11.1621,
+	"SEKJPYPMI": 7.0772,
+}
+
+func newServer(t *testing.T, seriesStatus int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/swea/v1/Series" {
+			w.WriteHeader(seriesStatus)
+			_, err := w.Write([]byte(seriesBody))
+			require.NoError(t, err)
+			return
+		}
+		var seriesId, from, to string
+		_, err := fmt.Sscanf(r.URL.Path, "/swea/v1/Observations/%9s", &seriesId)
+		require.NoError(t, err)
+		from = r.URL.Path[len("/swea/v1/Observations/"+seriesId+"/"):][:10]
+		to = r.URL.Path[len(r.URL.Path)-10:]
+		require.Equal(t, from, to)
+
+		value, ok := observations[seriesId]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// Weekends have no observations, answered with an empty list or a
+		// 404.
+		switch from {
+		case "2025-01-04":
+			_, err = w.Write([]byte(`[]`))
+		case "2025-01-05":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			_, err = fmt.Fprintf(w, `[{"date": %q, "value": %v}]`, from, value)
+		}
+		require.NoError(t, err)
+	}))
+}
+
+func TestRate(t *testing.T) {
+	t.Parallel()
+	jan3 := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+
+	for _, seriesStatus := range []int{http.StatusOK, http.StatusServiceUnavailable} {
+		seriesStatus := seriesStatus
+		t.Run(http.StatusText(seriesStatus), func(t *testing.T) {
+			t.Parallel()
+			testServer := newServer(t, seriesStatus)
+			defer testServer.Close()
+
+			api, err := riksbank.NewRiksbank(testServer.URL + "/swea/v1/")
+			require.NoError(t, err)
+
+			rate, err := api.Rate(context.Background(), document.EUR, document.SEK, jan3)
+			require.NoError(t, err)
+			require.Equal(t, converter.Rate{
+				From:     document.EUR,
+				To:       document.SEK,
+				Value:    11.4925,
+				Date:     jan3,
+				Provider: "riksbank",
+			}, rate)
+
+			// JPY is quoted per 100 yen, with or without the series list.
+			rate, err = api.Rate(context.Background(), document.Currency("JPY"), document.SEK, jan3)
+			require.NoError(t, err)
+			require.InDelta(t, 0.070772, rate.Value, 1e-12)
+
+			rate, err = api.Rate(context.Background(), document.USD, document.EUR, jan3)
+			require.NoError(t, err)
+			require.InDelta(t, 11.1621/11.4925, rate.Value, 1e-12)
+
+			_, err = api.Rate(context.Background(), document.EUR, document.SEK, time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC))
+			require.ErrorIs(t, err, converter.ErrNoRate)
+			_, err = api.Rate(context.Background(), document.EUR, document.SEK, time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC))
+			require.ErrorIs(t, err, converter.ErrNoRate)
+		})
+	}
+}
+
+func TestUnknownCurrency(t *testing.T) {
+	t.Parallel()
+	testServer := newServer(t, http.StatusOK)
+	defer testServer.Close()
+
+	api, err := riksbank.NewRiksbank(testServer.URL + "/swea/v1")
+	require.NoError(t, err)
+	_, err = api.Rate(context.Background(), document.Currency("ARS"), document.SEK, time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC))
+	require.ErrorIs(t, err, converter.ErrNoRate)
+}
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+	testServer := newServer(t, http.StatusOK)
+	defer testServer.Close()
+
+	api, err := riksbank.NewRiksbank(testServer.URL + "/swea/v1")
+	require.NoError(t, err)
+
+	jan3 := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+	gotDoc, failedDoc, err := api.Convert(context.Background(), document.SEK, &document.Document{
+		Transactions: []document.Transaction{
+			{Description: "a", Date: jan3, Amount: document.MustParseMoney("-10.00"), Currency: document.EUR},
+			{Description: "b", Date: jan3, Amount: document.NewMoney(1000, 0), Currency: document.Currency("JPY")},
+		},
+	})
+	require.NoError(t, err)
+	require.Empty(t, failedDoc.Transactions)
+	require.Equal(t, "-114.92", gotDoc.Transactions[0].Amount.String())
+	require.Equal(t, "70.77", gotDoc.Transactions[1].Amount.String())
+}
+
+func TestPrefetch(t *testing.T) {
+	t.Parallel()
+	jan := func(day int) time.Time {
+		return time.Date(2025, 1, day, 0, 0, 0, 0, time.UTC)
+	}
+	var requests []string
+	var mu sync.Mutex
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/swea/v1/Series" {
+			_, err := w.Write([]byte(seriesBody))
+			require.NoError(t, err)
+			return
+		}
+		mu.Lock()
+		requests = append(requests, r.URL.Path)
+		mu.Unlock()
+		// The 4th and 5th are a weekend, and nothing is published after the 6th yet.
+		_, err := w.Write([]byte(`[
+			{"date": "2025-01-02", "value": 11.5},
+			{"date": "2025-01-03", "value": 11.4925},
+			{"date": "2025-01-06", "value": 11.46}
+		]`))
+		require.NoError(t, err)
+	}))
+	defer testServer.Close()
+
+	api, err := riksbank.NewRiksbank(testServer.URL + "/swea/v1")
+	require.NoError(t, err)
+	require.NoError(t, api.Prefetch(context.Background(), document.EUR, document.SEK, jan(2), jan(8)))
+	require.Equal(t, []string{"/swea/v1/Observations/SEKEURPMI/2025-01-02/2025-01-08"}, requests)
+
+	rate, err := api.Rate(context.Background(), document.EUR, document.SEK, jan(6))
+	require.NoError(t, err)
+	require.Equal(t, 11.46, rate.Value)
+	_, err = api.Rate(context.Background(), document.EUR, document.SEK, jan(4))
+	require.ErrorIs(t, err, converter.ErrNoRate)
+	require.Len(t, requests, 1)
+
+	// Dates after the last observation are asked for again.
+	_, err = api.Rate(context.Background(), document.EUR, document.SEK, jan(7))
+	require.ErrorIs(t, err, converter.ErrNoRate)
+	require.Equal(t, "/swea/v1/Observations/SEKEURPMI/2025-01-07/2025-01-07", requests[1])
+}
+
+func TestSeriesAfterCancelledLookup(t *testing.T) {
+	t.Parallel()
+	var seriesRequests atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/swea/v1/Series" {
+			seriesRequests.Add(1)
+			// Quoted per 100 here, unlike the usual units, to tell the
+			// series list from the guess.
+			_, _ = w.Write([]byte(`[{"seriesId": "SEKUSDPMI", "midDescription": "100 USD"}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"date": "2025-01-03", "value": 1116.21}]`))
+	}))
+	defer testServer.Close()
+
+	api, err := riksbank.NewRiksbank(testServer.URL + "/swea/v1")
+	require.NoError(t, err)
+	jan3 := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+
+	// A cancelled lookup does not settle the units for the run.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = api.Rate(ctx, document.USD, document.SEK, jan3)
+	require.Error(t, err)
+
+	rate, err := api.Rate(context.Background(), document.USD, document.SEK, jan3)
+	require.NoError(t, err)
+	require.InDelta(t, 11.1621, rate.Value, 1e-12)
+	require.Equal(t, int32(1), seriesRequests.Load())
+}
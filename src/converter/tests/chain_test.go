@@ -0,0 +1,74 @@
+package convertertest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lazeratops/optimusdime/mocks"
+	"github.com/lazeratops/optimusdime/src/converter"
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+	date := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	first := mocks.NewRateProvider(t)
+	first.On("Name").Return("first").Maybe()
+	first.On("Rate", mock.Anything, document.SEK, document.EUR, date).Return(converter.Rate{}, converter.ErrFailedAPICall).Once()
+	first.On("Rate", mock.Anything, document.USD, document.EUR, date).Return(converter.Rate{Value: 0.9, Provider: "first"}, nil).Once()
+	first.On("Rate", mock.Anything, document.Currency("NOK"), document.EUR, date).Return(converter.Rate{}, converter.ErrNoRate).Once()
+
+	second := mocks.NewRateProvider(t)
+	second.On("Name").Return("second").Maybe()
+	second.On("Rate", mock.Anything, document.SEK, document.EUR, date).Return(converter.Rate{Value: 0.1}, nil).Once()
+	second.On("Rate", mock.Anything, document.Currency("NOK"), document.EUR, date).Return(converter.Rate{}, converter.ErrNoRate).Once()
+
+	chain := converter.NewChain(first, second)
+	require.Equal(t, "first,second", chain.Name())
+
+	rate, err := chain.Rate(context.Background(), document.SEK, document.EUR, date)
+	require.NoError(t, err)
+	require.Equal(t, converter.Rate{Value: 0.1, Provider: "second"}, rate)
+
+	// Later providers are not asked once one has answered.
+	rate, err = chain.Rate(context.Background(), document.USD, document.EUR, date)
+	require.NoError(t, err)
+	require.Equal(t, "first", rate.Provider)
+
+	_, err = chain.Rate(context.Background(), document.Currency("NOK"), document.EUR, date)
+	require.ErrorIs(t, err, converter.ErrNoRate)
+	require.ErrorContains(t, err, "first: ")
+	require.ErrorContains(t, err, "second: ")
+}
+
+func TestChainFatal(t *testing.T) {
+	t.Parallel()
+	date := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	fatal := fmt.Errorf("bad key: %w", converter.ErrFatal)
+
+	first := mocks.NewRateProvider(t)
+	first.On("Name").Return("first").Maybe()
+	// A provider that failed fatally is not asked again.
+	first.On("Rate", mock.Anything, document.SEK, document.EUR, date).Return(converter.Rate{}, fatal).Once()
+
+	second := mocks.NewRateProvider(t)
+	second.On("Name").Return("second").Maybe()
+	second.On("Rate", mock.Anything, document.SEK, document.EUR, date).Return(converter.Rate{}, converter.ErrNoRate).Once()
+	second.On("Rate", mock.Anything, document.USD, document.EUR, date).Return(converter.Rate{}, fatal).Once()
+
+	chain := converter.NewChain(first, second)
+
+	// The chain is only unusable once every provider is.
+	_, err := chain.Rate(context.Background(), document.SEK, document.EUR, date)
+	require.ErrorIs(t, err, converter.ErrNoRate)
+	require.NotErrorIs(t, err, converter.ErrFatal)
+	require.ErrorContains(t, err, "first: bad key")
+
+	_, err = chain.Rate(context.Background(), document.USD, document.EUR, date)
+	require.ErrorIs(t, err, converter.ErrFatal)
+}
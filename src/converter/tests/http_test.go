@@ -0,0 +1,131 @@
+package convertertest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/converter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetch(t *testing.T) {
+	t.Parallel()
+	policy := converter.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    10 * time.Millisecond,
+	}
+
+	cases := []struct {
+		name         string
+		statuses     []int
+		wantBody     string
+		wantErr      error
+		wantRequests int32
+	}{
+		{
+			name:         "ok",
+			statuses:     []int{http.StatusOK},
+			wantBody:     "ok",
+			wantRequests: 1,
+		},
+		{
+			name:         "retries rate limited",
+			statuses:     []int{http.StatusTooManyRequests, http.StatusOK},
+			wantBody:     "ok",
+			wantRequests: 2,
+		},
+		{
+			name:         "does not retry not found",
+			statuses:     []int{http.StatusNotFound, http.StatusOK},
+			wantErr:      converter.ErrNotFound,
+			wantRequests: 1,
+		},
+		{
+			name:         "does not retry bad request",
+			statuses:     []int{http.StatusBadRequest, http.StatusOK},
+			wantErr:      converter.ErrFailedAPICall,
+			wantRequests: 1,
+		},
+		{
+			name:         "gives up after max attempts",
+			statuses:     []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			wantErr:      converter.ErrFailedAPICall,
+			wantRequests: 3,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tc.statuses[requests.Add(1)-1]
+				if status == http.StatusTooManyRequests {
+					w.Header().Set("Retry-After", "0")
+				}
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte("ok"))
+			}))
+			defer server.Close()
+
+			body, err := converter.Fetch(context.Background(), server.Client(), policy, server.URL)
+			require.ErrorIs(t, err, tc.wantErr)
+			if err == nil {
+				require.Equal(t, tc.wantBody, string(body))
+			}
+			require.Equal(t, tc.wantRequests, requests.Load())
+		})
+	}
+}
+
+func TestFetchCancelled(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	policy := converter.RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: time.Minute}
+
+	start := time.Now()
+	_, err := converter.Fetch(ctx, server.Client(), policy, server.URL)
+	require.ErrorIs(t, err, converter.ErrFailedAPICall)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestFetchRetriesNetworkErrors(t *testing.T) {
+	t.Parallel()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Drop the first connection without a response.
+		if requests.Add(1) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			conn.Close()
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	policy := converter.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	body, err := converter.Fetch(context.Background(), server.Client(), policy, server.URL)
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(body))
+	require.Equal(t, int32(2), requests.Load())
+
+	// A done context is not retried.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = converter.Fetch(ctx, server.Client(), policy, server.URL)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, int32(2), requests.Load())
+}
@@ -0,0 +1,117 @@
+package convertertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/converter"
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/stretchr/testify/require"
+)
+
+// slowProvider takes a while per lookup and records how many ran at once.
+type slowProvider struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	calls       int
+}
+
+func (p *slowProvider) Name() string {
+	return "slow"
+}
+
+func (p *slowProvider) Rate(ctx context.Context, from document.Currency, to document.Currency, date time.Time) (converter.Rate, error) {
+	p.mu.Lock()
+	p.inFlight++
+	p.calls++
+	p.maxInFlight = max(p.maxInFlight, p.inFlight)
+	p.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	p.mu.Lock()
+	p.inFlight--
+	p.mu.Unlock()
+	return converter.Rate{Value: float64(date.Day())}, nil
+}
+
+func TestRateConverterParallelism(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	statement := &document.Document{}
+	for i := range 60 {
+		// Every date appears twice, out of order.
+		date := start.AddDate(0, 0, (i*7)%30)
+		statement.Transactions = append(statement.Transactions, document.Transaction{
+			Description: fmt.Sprint(i),
+			Date:        date,
+			Amount:      document.MustParseMoney("1.00"),
+			Currency:    document.SEK,
+		})
+	}
+
+	provider := &slowProvider{}
+	c := converter.NewRateConverter(provider)
+	c.SetParallelism(3)
+	gotDoc, failedDoc, err := c.Convert(context.Background(), document.EUR, statement)
+	require.NoError(t, err)
+	require.Empty(t, failedDoc.Transactions)
+
+	require.Equal(t, 30, provider.calls)
+	require.LessOrEqual(t, provider.maxInFlight, 3)
+	require.Greater(t, provider.maxInFlight, 1)
+
+	require.Len(t, gotDoc.Transactions, len(statement.Transactions))
+	for i, got := range gotDoc.Transactions {
+		want := statement.Transactions[i]
+		require.Equal(t, want.Description, got.Description)
+		require.Equal(t, float64(want.Date.Day()), got.Conversion.Rate)
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	client := converter.NewRateLimitedClient(server.Client(), 100)
+
+	began := time.Now()
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := converter.Fetch(context.Background(), client, converter.DefaultRetryPolicy, server.URL)
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	// Five requests at 100 a second start at least 40ms apart in total.
+	require.GreaterOrEqual(t, time.Since(began), 40*time.Millisecond)
+
+	// Answers from memory send no request, so they do not wait.
+	var memo converter.Memo[string, string]
+	_, err := memo.Do("SEK", func() (string, error) {
+		body, err := converter.Fetch(context.Background(), client, converter.DefaultRetryPolicy, server.URL)
+		return string(body), err
+	})
+	require.NoError(t, err)
+	began = time.Now()
+	for range 50 {
+		got, err := memo.Do("SEK", func() (string, error) {
+			return "", errors.New("fetched again")
+		})
+		require.NoError(t, err)
+		require.Equal(t, "ok", got)
+	}
+	require.Less(t, time.Since(began), 100*time.Millisecond)
+}
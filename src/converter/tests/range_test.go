@@ -0,0 +1,72 @@
+package convertertest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lazeratops/optimusdime/mocks"
+	"github.com/lazeratops/optimusdime/src/converter"
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateConverterPrefetch(t *testing.T) {
+	t.Parallel()
+	jan := func(day int) time.Time {
+		return time.Date(2025, 1, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	provider := mocks.NewRangeProvider(t)
+	provider.On("Name").Return("fake").Maybe()
+	// Each currency's span of dates, widened by the fallback's two days
+	// back, is prefetched once. EUR is already in the target currency.
+	provider.On("Prefetch", mock.Anything, document.SEK, document.EUR, jan(1), jan(9)).Return(nil).Once()
+	provider.On("Prefetch", mock.Anything, document.USD, document.EUR, jan(3), jan(5)).Return(converter.ErrFailedAPICall).Once()
+	provider.On("Rate", mock.Anything, mock.Anything, document.EUR, mock.Anything).Return(converter.Rate{Value: 0.5}, nil)
+
+	statement := &document.Document{
+		Transactions: []document.Transaction{
+			{Description: "a", Date: jan(9), Amount: document.MustParseMoney("10.00"), Currency: document.SEK},
+			{Description: "b", Date: jan(5), Amount: document.MustParseMoney("10.00"), Currency: document.USD},
+			{Description: "c", Date: jan(3), Amount: document.MustParseMoney("10.00"), Currency: document.SEK},
+			{Description: "d", Date: jan(4), Amount: document.MustParseMoney("10.00"), Currency: document.EUR},
+		},
+	}
+
+	c := converter.NewRateConverter(provider)
+	c.SetFallback(converter.FallbackPrevious, 2)
+	gotDoc, failedDoc, err := c.Convert(context.Background(), document.EUR, statement)
+	require.NoError(t, err)
+	// A failed prefetch leaves the lookups to Rate.
+	require.Len(t, gotDoc.Transactions, 4)
+	require.Empty(t, failedDoc.Transactions)
+}
+
+func TestChainPrefetch(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	// The provider after a failed one is prefetched instead.
+	failing := mocks.NewRangeProvider(t)
+	failing.On("Name").Return("failing").Maybe()
+	failing.On("Prefetch", mock.Anything, document.SEK, document.EUR, start, end).Return(converter.ErrFailedAPICall).Once()
+	ranged := mocks.NewRangeProvider(t)
+	ranged.On("Name").Return("ranged").Maybe()
+	ranged.On("Prefetch", mock.Anything, document.SEK, document.EUR, start, end).Return(nil).Once()
+
+	chain := converter.NewChain(failing, ranged)
+	require.NoError(t, chain.Prefetch(context.Background(), document.SEK, document.EUR, start, end))
+
+	// A provider that fetches by date answers most lookups, so the ones
+	// after it are not prefetched.
+	byDate := mocks.NewRateProvider(t)
+	byDate.On("Name").Return("bydate").Maybe()
+	unused := mocks.NewRangeProvider(t)
+	unused.On("Name").Return("unused").Maybe()
+
+	chain = converter.NewChain(byDate, unused)
+	require.NoError(t, chain.Prefetch(context.Background(), document.SEK, document.EUR, start, end))
+}
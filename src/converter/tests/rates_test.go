@@ -0,0 +1,177 @@
+package convertertest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lazeratops/optimusdime/mocks"
+	"github.com/lazeratops/optimusdime/src/converter"
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateConverter(t *testing.T) {
+	t.Parallel()
+	day1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	provider := mocks.NewRateProvider(t)
+	provider.On("Name").Return("fake").Maybe()
+	provider.On("Rate", mock.Anything, document.SEK, document.EUR, day1).Return(converter.Rate{
+		From:     document.SEK,
+		To:       document.EUR,
+		Value:    0.1,
+		Date:     day1.AddDate(0, 0, -1),
+		Provider: "fake",
+	}, nil).Once()
+	provider.On("Rate", mock.Anything, document.USD, document.EUR, day1).Return(converter.Rate{}, errors.New("boom")).Once()
+	// A rate without a date applies to the transaction date.
+	provider.On("Rate", mock.Anything, document.SEK, document.EUR, day2).Return(converter.Rate{Value: 0.2}, nil).Once()
+
+	statement := &document.Document{
+		Transactions: []document.Transaction{
+			{Description: "a", Date: day1, Amount: document.MustParseMoney("10.05"), Currency: document.SEK},
+			{Description: "b", Date: day1, Amount: document.MustParseMoney("5.00"), Currency: document.USD},
+			{Description: "c", Date: day2, Amount: document.MustParseMoney("-20.00"), Currency: document.SEK},
+			{Description: "d", Date: day1, Amount: document.MustParseMoney("1.00"), Currency: document.SEK, Reference: "ref"},
+		},
+	}
+
+	c := converter.NewRateConverter(provider)
+	c.SetRounding(document.HalfUp)
+	gotDoc, failedDoc, err := c.Convert(context.Background(), document.EUR, statement)
+	require.NoError(t, err)
+
+	require.Equal(t, []document.Transaction{
+		{
+			Description: "a", Date: day1, Amount: document.MustParseMoney("1.01"), Currency: document.EUR,
+			Conversion: &document.Conversion{
+				OriginalAmount: document.MustParseMoney("10.05"), OriginalCurrency: document.SEK,
+				Rate: 0.1, RateDate: day1.AddDate(0, 0, -1), RateProvider: "fake",
+			},
+		},
+		{
+			Description: "c", Date: day2, Amount: document.MustParseMoney("-4.00"), Currency: document.EUR,
+			Conversion: &document.Conversion{
+				OriginalAmount: document.MustParseMoney("-20.00"), OriginalCurrency: document.SEK,
+				Rate: 0.2, RateDate: day2, RateProvider: "fake",
+			},
+		},
+		{
+			Description: "d", Date: day1, Amount: document.MustParseMoney("0.10"), Currency: document.EUR, Reference: "ref",
+			Conversion: &document.Conversion{
+				OriginalAmount: document.MustParseMoney("1.00"), OriginalCurrency: document.SEK,
+				Rate: 0.1, RateDate: day1.AddDate(0, 0, -1), RateProvider: "fake",
+			},
+		},
+	}, gotDoc.Transactions)
+	require.Equal(t, []document.Transaction{statement.Transactions[1]}, failedDoc.Transactions)
+}
+
+func TestRateConverterAllFailed(t *testing.T) {
+	t.Parallel()
+	provider := mocks.NewRateProvider(t)
+	provider.On("Name").Return("fake").Maybe()
+	provider.On("Rate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(converter.Rate{}, converter.ErrNoRate)
+
+	statement := &document.Document{
+		Transactions: []document.Transaction{
+			{Description: "a", Date: time.Now(), Amount: document.MustParseMoney("1.00"), Currency: document.SEK},
+		},
+	}
+	_, failedDoc, err := converter.NewRateConverter(provider).Convert(context.Background(), document.EUR, statement)
+	require.ErrorIs(t, err, converter.ErrNoRate)
+	require.Equal(t, statement.Transactions, failedDoc.Transactions)
+}
+
+func TestRateConverterFallback(t *testing.T) {
+	t.Parallel()
+	friday := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+	saturday := friday.AddDate(0, 0, 1)
+	sunday := friday.AddDate(0, 0, 2)
+	monday := friday.AddDate(0, 0, 3)
+
+	cases := []struct {
+		name     string
+		policy   converter.FallbackPolicy
+		maxDays  int
+		wantDate time.Time
+		wantErr  error
+	}{
+		{name: "fail", policy: converter.FallbackFail, maxDays: 5, wantErr: converter.ErrNoRate},
+		{name: "previous", policy: converter.FallbackPrevious, maxDays: 5, wantDate: friday},
+		{name: "next", policy: converter.FallbackNext, maxDays: 5, wantDate: monday},
+		{name: "nearest", policy: converter.FallbackNearest, maxDays: 5, wantDate: monday},
+		{name: "too far", policy: converter.FallbackPrevious, maxDays: 1, wantErr: converter.ErrNoRate},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			provider := mocks.NewRateProvider(t)
+			provider.On("Name").Return("fake").Maybe()
+			provider.On("Rate", mock.Anything, document.SEK, document.EUR, saturday).Return(converter.Rate{}, converter.ErrNoRate).Maybe()
+			provider.On("Rate", mock.Anything, document.SEK, document.EUR, sunday).Return(converter.Rate{}, converter.ErrNoRate).Maybe()
+			provider.On("Rate", mock.Anything, document.SEK, document.EUR, friday).Return(converter.Rate{Value: 0.1}, nil).Maybe()
+			provider.On("Rate", mock.Anything, document.SEK, document.EUR, monday).Return(converter.Rate{Value: 0.2}, nil).Maybe()
+
+			c := converter.NewRateConverter(provider)
+			c.SetFallback(tc.policy, tc.maxDays)
+			gotDoc, _, err := c.Convert(context.Background(), document.EUR, &document.Document{
+				Transactions: []document.Transaction{
+					{Description: "card", Date: sunday, Amount: document.MustParseMoney("10.00"), Currency: document.SEK},
+				},
+			})
+			require.ErrorIs(t, err, tc.wantErr)
+			if err == nil {
+				got := gotDoc.Transactions[0]
+				require.Equal(t, sunday, got.Date)
+				require.Equal(t, tc.wantDate, got.Conversion.RateDate)
+			}
+		})
+	}
+}
+
+func TestRateConverterFallbackOnlyForMissingRates(t *testing.T) {
+	t.Parallel()
+	date := time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC)
+	provider := mocks.NewRateProvider(t)
+	provider.On("Name").Return("fake").Maybe()
+	provider.On("Rate", mock.Anything, document.SEK, document.EUR, date).Return(converter.Rate{}, converter.ErrFailedAPICall).Once()
+
+	c := converter.NewRateConverter(provider)
+	c.SetFallback(converter.FallbackPrevious, 5)
+	_, _, err := c.Convert(context.Background(), document.EUR, &document.Document{
+		Transactions: []document.Transaction{
+			{Description: "card", Date: date, Amount: document.MustParseMoney("10.00"), Currency: document.SEK},
+		},
+	})
+	require.ErrorIs(t, err, converter.ErrFailedAPICall)
+}
+
+func TestRateConverterStopsOnFatal(t *testing.T) {
+	t.Parallel()
+	day1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	provider := mocks.NewRateProvider(t)
+	provider.On("Name").Return("fake").Maybe()
+	provider.On("Rate", mock.Anything, document.SEK, document.EUR, day1).Return(converter.Rate{Value: 0.1}, nil).Once()
+	// Neither the fallback nor the remaining dates are tried.
+	provider.On("Rate", mock.Anything, document.SEK, document.EUR, day1.AddDate(0, 0, 1)).Return(converter.Rate{}, converter.ErrFatal).Once()
+
+	c := converter.NewRateConverter(provider)
+	c.SetParallelism(1)
+	c.SetFallback(converter.FallbackPrevious, 5)
+	gotDoc, failedDoc, err := c.Convert(context.Background(), document.EUR, &document.Document{
+		Transactions: []document.Transaction{
+			{Description: "a", Date: day1, Amount: document.MustParseMoney("1.00"), Currency: document.SEK},
+			{Description: "b", Date: day1.AddDate(0, 0, 1), Amount: document.MustParseMoney("1.00"), Currency: document.SEK},
+			{Description: "c", Date: day1.AddDate(0, 0, 2), Amount: document.MustParseMoney("1.00"), Currency: document.SEK},
+		},
+	})
+	require.ErrorIs(t, err, converter.ErrFatal)
+	require.Nil(t, gotDoc)
+	require.Nil(t, failedDoc)
+}
@@ -0,0 +1,294 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+type Currency string
+
+const (
+	USD Currency = "USD"
+	SEK Currency = "SEK"
+	EUR Currency = "EUR"
+)
+
+func (c Currency) String() string {
+	return strings.ToUpper(string(c))
+}
+
+// CurrencyInfo is an ISO 4217 registry entry.
+type CurrencyInfo struct {
+	Code       Currency
+	Numeric    string
+	MinorUnits int
+	Name       string
+	// Symbols are written next to amounts, e.g. "kr" or "€". A symbol
+	// shared by several currencies only maps back to the one in
+	// preferredSymbols, or to none.
+	Symbols []string
+}
+
+// iso4217 lists the active ISO 4217 currencies, excluding funds and
+// precious metals.
+var iso4217 = []CurrencyInfo{
+	{"AED", "784", 2, "UAE Dirham", []string{"د.إ"}},
+	{"AFN", "971", 2, "Afghani", []string{"؋"}},
+	{"ALL", "008", 2, "Lek", nil},
+	{"AMD", "051", 2, "Armenian Dram", []string{"֏"}},
+	{"ANG", "532", 2, "Netherlands Antillean Guilder", []string{"ƒ"}},
+	{"AOA", "973", 2, "Kwanza", []string{"Kz"}},
+	{"ARS", "032", 2, "Argentine Peso", []string{"$"}},
+	{"AUD", "036", 2, "Australian Dollar", []string{"$", "A$", "AU$"}},
+	{"AWG", "533", 2, "Aruban Florin", []string{"ƒ"}},
+	{"AZN", "944", 2, "Azerbaijan Manat", []string{"₼"}},
+	{"BAM", "977", 2, "Convertible Mark", []string{"KM"}},
+	{"BBD", "052", 2, "Barbados Dollar", []string{"$", "Bds$"}},
+	{"BDT", "050", 2, "Taka", []string{"৳"}},
+	{"BGN", "975", 2, "Bulgarian Lev", []string{"лв"}},
+	{"BHD", "048", 3, "Bahraini Dinar", nil},
+	{"BIF", "108", 0, "Burundi Franc", nil},
+	{"BMD", "060", 2, "Bermudian Dollar", []string{"$"}},
+	{"BND", "096", 2, "Brunei Dollar", []string{"$"}},
+	{"BOB", "068", 2, "Boliviano", []string{"Bs."}},
+	{"BRL", "986", 2, "Brazilian Real", []string{"R$"}},
+	{"BSD", "044", 2, "Bahamian Dollar", []string{"$"}},
+	{"BTN", "064", 2, "Ngultrum", nil},
+	{"BWP", "072", 2, "Pula", nil},
+	{"BYN", "933", 2, "Belarusian Ruble", []string{"Br"}},
+	{"BZD", "084", 2, "Belize Dollar", []string{"$"}},
+	{"CAD", "124", 2, "Canadian Dollar", []string{"$", "C$", "CA$"}},
+	{"CDF", "976", 2, "Congolese Franc", nil},
+	{"CHF", "756", 2, "Swiss Franc", []string{"Fr", "Fr."}},
+	{"CLF", "990", 4, "Unidad de Fomento", nil},
+	{"CLP", "152", 0, "Chilean Peso", []string{"$"}},
+	{"CNY", "156", 2, "Yuan Renminbi", []string{"¥", "元", "CN¥"}},
+	{"COP", "170", 2, "Colombian Peso", []string{"$"}},
+	{"CRC", "188", 2, "Costa Rican Colon", []string{"₡"}},
+	{"CUP", "192", 2, "Cuban Peso", []string{"$"}},
+	{"CVE", "132", 2, "Cabo Verde Escudo", nil},
+	{"CZK", "203", 2, "Czech Koruna", []string{"Kč"}},
+	{"DJF", "262", 0, "Djibouti Franc", nil},
+	{"DKK", "208", 2, "Danish Krone", []string{"kr", "kr."}},
+	{"DOP", "214", 2, "Dominican Peso", []string{"$", "RD$"}},
+	{"DZD", "012", 2, "Algerian Dinar", nil},
+	{"EGP", "818", 2, "Egyptian Pound", []string{"E£"}},
+	{"ERN", "232", 2, "Nakfa", nil},
+	{"ETB", "230", 2, "Ethiopian Birr", []string{"Br"}},
+	{"EUR", "978", 2, "Euro", []string{"€"}},
+	{"FJD", "242", 2, "Fiji Dollar", []string{"$"}},
+	{"FKP", "238", 2, "Falkland Islands Pound", []string{"£"}},
+	{"GBP", "826", 2, "Pound Sterling", []string{"£"}},
+	{"GEL", "981", 2, "Lari", []string{"₾"}},
+	{"GHS", "936", 2, "Ghana Cedi", []string{"₵"}},
+	{"GIP", "292", 2, "Gibraltar Pound", []string{"£"}},
+	{"GMD", "270", 2, "Dalasi", nil},
+	{"GNF", "324", 0, "Guinean Franc", nil},
+	{"GTQ", "320", 2, "Quetzal", nil},
+	{"GYD", "328", 2, "Guyana Dollar", []string{"$"}},
+	{"HKD", "344", 2, "Hong Kong Dollar", []string{"$", "HK$"}},
+	{"HNL", "340", 2, "Lempira", nil},
+	{"HTG", "332", 2, "Gourde", nil},
+	{"HUF", "348", 2, "Forint", []string{"Ft"}},
+	{"IDR", "360", 2, "Rupiah", []string{"Rp"}},
+	{"ILS", "376", 2, "New Israeli Sheqel", []string{"₪"}},
+	{"INR", "356", 2, "Indian Rupee", []string{"₹"}},
+	{"IQD", "368", 3, "Iraqi Dinar", nil},
+	{"IRR", "364", 2, "Iranian Rial", []string{"﷼"}},
+	{"ISK", "352", 0, "Iceland Krona", []string{"kr"}},
+	{"JMD", "388", 2, "Jamaican Dollar", []string{"$", "J$"}},
+	{"JOD", "400", 3, "Jordanian Dinar", nil},
+	{"JPY", "392", 0, "Yen", []string{"¥", "円"}},
+	{"KES", "404", 2, "Kenyan Shilling", []string{"KSh"}},
+	{"KGS", "417", 2, "Som", nil},
+	{"KHR", "116", 2, "Riel", []string{"៛"}},
+	{"KMF", "174", 0, "Comorian Franc", nil},
+	{"KPW", "408", 2, "North Korean Won", []string{"₩"}},
+	{"KRW", "410", 0, "Won", []string{"₩"}},
+	{"KWD", "414", 3, "Kuwaiti Dinar", nil},
+	{"KYD", "136", 2, "Cayman Islands Dollar", []string{"$"}},
+	{"KZT", "398", 2, "Tenge", []string{"₸"}},
+	{"LAK", "418", 2, "Lao Kip", []string{"₭"}},
+	{"LBP", "422", 2, "Lebanese Pound", nil},
+	{"LKR", "144", 2, "Sri Lanka Rupee", nil},
+	{"LRD", "430", 2, "Liberian Dollar", []string{"$"}},
+	{"LSL", "426", 2, "Loti", nil},
+	{"LYD", "434", 3, "Libyan Dinar", nil},
+	{"MAD", "504", 2, "Moroccan Dirham", nil},
+	{"MDL", "498", 2, "Moldovan Leu", nil},
+	{"MGA", "969", 2, "Malagasy Ariary", nil},
+	{"MKD", "807", 2, "Denar", nil},
+	{"MMK", "104", 2, "Kyat", nil},
+	{"MNT", "496", 2, "Tugrik", []string{"₮"}},
+	{"MOP", "446", 2, "Pataca", nil},
+	{"MRU", "929", 2, "Ouguiya", nil},
+	{"MUR", "480", 2, "Mauritius Rupee", nil},
+	{"MVR", "462", 2, "Rufiyaa", nil},
+	{"MWK", "454", 2, "Malawi Kwacha", nil},
+	{"MXN", "484", 2, "Mexican Peso", []string{"$", "MX$"}},
+	{"MYR", "458", 2, "Malaysian Ringgit", []string{"RM"}},
+	{"MZN", "943", 2, "Mozambique Metical", nil},
+	{"NAD", "516", 2, "Namibia Dollar", []string{"$"}},
+	{"NGN", "566", 2, "Naira", []string{"₦"}},
+	{"NIO", "558", 2, "Cordoba Oro", nil},
+	{"NOK", "578", 2, "Norwegian Krone", []string{"kr"}},
+	{"NPR", "524", 2, "Nepalese Rupee", nil},
+	{"NZD", "554", 2, "New Zealand Dollar", []string{"$", "NZ$"}},
+	{"OMR", "512", 3, "Rial Omani", nil},
+	{"PAB", "590", 2, "Balboa", nil},
+	{"PEN", "604", 2, "Sol", []string{"S/"}},
+	{"PGK", "598", 2, "Kina", nil},
+	{"PHP", "608", 2, "Philippine Peso", []string{"₱"}},
+	{"PKR", "586", 2, "Pakistan Rupee", nil},
+	{"PLN", "985", 2, "Zloty", []string{"zł"}},
+	{"PYG", "600", 0, "Guarani", []string{"₲"}},
+	{"QAR", "634", 2, "Qatari Rial", nil},
+	{"RON", "946", 2, "Romanian Leu", []string{"lei"}},
+	{"RSD", "941", 2, "Serbian Dinar", nil},
+	{"RUB", "643", 2, "Russian Ruble", []string{"₽"}},
+	{"RWF", "646", 0, "Rwanda Franc", nil},
+	{"SAR", "682", 2, "Saudi Riyal", nil},
+	{"SBD", "090", 2, "Solomon Islands Dollar", []string{"$"}},
+	{"SCR", "690", 2, "Seychelles Rupee", nil},
+	{"SDG", "938", 2, "Sudanese Pound", nil},
+	{"SEK", "752", 2, "Swedish Krona", []string{"kr", "kr."}},
+	{"SGD", "702", 2, "Singapore Dollar", []string{"$", "S$"}},
+	{"SHP", "654", 2, "Saint Helena Pound", []string{"£"}},
+	{"SLE", "925", 2, "Leone", nil},
+	{"SOS", "706", 2, "Somali Shilling", nil},
+	{"SRD", "968", 2, "Surinam Dollar", []string{"$"}},
+	{"SSP", "728", 2, "South Sudanese Pound", nil},
+	{"STN", "930", 2, "Dobra", nil},
+	{"SVC", "222", 2, "El Salvador Colon", nil},
+	{"SYP", "760", 2, "Syrian Pound", nil},
+	{"SZL", "748", 2, "Lilangeni", nil},
+	{"THB", "764", 2, "Baht", []string{"฿"}},
+	{"TJS", "972", 2, "Somoni", nil},
+	{"TMT", "934", 2, "Turkmenistan New Manat", nil},
+	{"TND", "788", 3, "Tunisian Dinar", nil},
+	{"TOP", "776", 2, "Pa'anga", nil},
+	{"TRY", "949", 2, "Turkish Lira", []string{"₺"}},
+	{"TTD", "780", 2, "Trinidad and Tobago Dollar", []string{"$", "TT$"}},
+	{"TWD", "901", 2, "New Taiwan Dollar", []string{"$", "NT$"}},
+	{"TZS", "834", 2, "Tanzanian Shilling", nil},
+	{"UAH", "980", 2, "Hryvnia", []string{"₴"}},
+	{"UGX", "800", 0, "Uganda Shilling", nil},
+	{"USD", "840", 2, "US Dollar", []string{"$", "US$"}},
+	{"UYI", "940", 0, "Uruguay Peso en Unidades Indexadas", nil},
+	{"UYU", "858", 2, "Peso Uruguayo", []string{"$"}},
+	{"UYW", "927", 4, "Unidad Previsional", nil},
+	{"UZS", "860", 2, "Uzbekistan Sum", nil},
+	{"VED", "926", 2, "Bolívar Soberano", nil},
+	{"VES", "928", 2, "Bolívar Soberano", nil},
+	{"VND", "704", 0, "Dong", []string{"₫"}},
+	{"VUV", "548", 0, "Vatu", nil},
+	{"WST", "882", 2, "Tala", nil},
+	{"XAF", "950", 0, "CFA Franc BEAC", nil},
+	{"XCD", "951", 2, "East Caribbean Dollar", []string{"$", "EC$"}},
+	{"XOF", "952", 0, "CFA Franc BCEAO", nil},
+	{"XPF", "953", 0, "CFP Franc", nil},
+	{"YER", "886", 2, "Yemeni Rial", nil},
+	{"ZAR", "710", 2, "Rand", nil},
+	{"ZMW", "967", 2, "Zambian Kwacha", nil},
+	{"ZWG", "924", 2, "Zimbabwe Gold", nil},
+}
+
+// preferredSymbols settles symbols shared by several currencies.
+var preferredSymbols = map[string]Currency{
+	"$":  USD,
+	"£":  "GBP",
+	"¥":  "JPY",
+	"₩":  "KRW",
+	"kr": SEK,
+	"ƒ":  "ANG",
+}
+
+var (
+	currenciesByCode    = make(map[Currency]*CurrencyInfo, len(iso4217))
+	currenciesByNumeric = make(map[string]*CurrencyInfo, len(iso4217))
+	currenciesBySymbol  = make(map[string]Currency)
+)
+
+func init() {
+	shared := make(map[string]bool)
+	for i := range iso4217 {
+		info := &iso4217[i]
+		currenciesByCode[info.Code] = info
+		currenciesByNumeric[info.Numeric] = info
+		for _, s := range info.Symbols {
+			key := strings.ToLower(s)
+			if c, ok := currenciesBySymbol[key]; ok && c != info.Code {
+				shared[key] = true
+			}
+			currenciesBySymbol[key] = info.Code
+		}
+	}
+	for s := range shared {
+		delete(currenciesBySymbol, s)
+	}
+	for s, c := range preferredSymbols {
+		currenciesBySymbol[strings.ToLower(s)] = c
+	}
+}
+
+// Currencies returns the registry, ordered by code.
+func Currencies() []CurrencyInfo {
+	list := make([]CurrencyInfo, len(iso4217))
+	copy(list, iso4217)
+	return list
+}
+
+// Info returns the registry entry for an exact ISO 4217 code.
+func (c Currency) Info() (CurrencyInfo, bool) {
+	info, ok := currenciesByCode[c]
+	if !ok {
+		return CurrencyInfo{}, false
+	}
+	return *info, true
+}
+
+// Valid reports whether c is an upper-case ISO 4217 code.
+func (c Currency) Valid() bool {
+	_, ok := currenciesByCode[c]
+	return ok
+}
+
+// Normalize maps a code in any case, a numeric code such as "752" or an
+// unambiguous symbol such as "€" to its ISO 4217 code.
+func (c Currency) Normalize() (Currency, error) {
+	s := strings.TrimSpace(string(c))
+	if info, ok := currenciesByCode[Currency(strings.ToUpper(s))]; ok {
+		return info.Code, nil
+	}
+	if info, ok := currenciesByNumeric[s]; ok {
+		return info.Code, nil
+	}
+	if code, ok := currenciesBySymbol[strings.ToLower(s)]; ok {
+		return code, nil
+	}
+	return "", fmt.Errorf("%q: %w", string(c), ErrUnknownCurrency)
+}
+
+// MinorUnits returns the number of decimals the currency is written with,
+// e.g. 2 for SEK and 0 for JPY. Unknown currencies get 2.
+func (c Currency) MinorUnits() int {
+	if info, ok := currenciesByCode[Currency(c.String())]; ok {
+		return info.MinorUnits
+	}
+	return 2
+}
+
+// CurrencyFromSymbol maps a symbol or code written next to an amount, such
+// as "kr", "€" or "eur", to a currency.
+func CurrencyFromSymbol(s string) (Currency, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.IndexFunc(s, unicode.IsDigit) >= 0 {
+		return "", false
+	}
+	c, err := Currency(s).Normalize()
+	return c, err == nil
+}
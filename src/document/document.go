@@ -5,22 +5,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"strings"
+	"strconv"
 	"time"
 )
 
-type Currency string
+// CurrencySource records where a transaction's currency was read from.
+type CurrencySource string
 
 const (
-	USD Currency = "USD"
-	SEK Currency = "SEK"
-	EUR Currency = "EUR"
+	CurrencySourceColumn   CurrencySource = "column"
+	CurrencySourceAmount   CurrencySource = "amount"
+	CurrencySourceDefault  CurrencySource = "default"
+	CurrencySourceProfile  CurrencySource = "profile"
+	CurrencySourceHeader   CurrencySource = "header"
+	CurrencySourceMetadata CurrencySource = "metadata"
 )
 
-func (c Currency) String() string {
-	return strings.ToUpper(string(c))
-}
-
 type Document struct {
 	Transactions []Transaction `json:"transactions" jsonschema_description:"All bank transactions in the document"`
 }
@@ -28,8 +28,25 @@ type Document struct {
 type Transaction struct {
 	Description string    `json:"description" jsonschema_description:"The description of the transaction"`
 	Date        time.Time `json:"date" jsonschema_description:"The date of the transaction"`
-	Amount      float64   `json:"amount" jsonschema_description:"The amount of the transaction"`
+	Amount      Money     `json:"amount" jsonschema_description:"The amount of the transaction"`
 	Currency    Currency  `json:"currency" jsonschema_description:"The currency of the transaction"`
+	Reference   string    `json:"reference,omitempty" jsonschema_description:"The bank's reference for the transaction"`
+
+	CurrencySource CurrencySource `json:"currency_source,omitempty" jsonschema_description:"Where the currency was read from"`
+	// Conversion is set on transactions produced by a converter.
+	Conversion *Conversion `json:"conversion,omitempty" jsonschema_description:"How the amount was converted, if it was"`
+}
+
+// Conversion records where a converted amount came from, for audits.
+type Conversion struct {
+	OriginalAmount   Money    `json:"original_amount"`
+	OriginalCurrency Currency `json:"original_currency"`
+	// Rate is the number of target currency units per original unit.
+	Rate float64 `json:"rate"`
+	// RateDate is the date the rate applies to, which can differ from the
+	// transaction date when the provider has no rate for that day.
+	RateDate     time.Time `json:"rate_date"`
+	RateProvider string    `json:"rate_provider"`
 }
 
 func (t *Transaction) UnmarshalJSON(data []byte) error {
@@ -45,8 +62,11 @@ func (t *Transaction) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	// Parse the date
+	// Parse the date. LLM output uses DD-MM-YYYY, JSON exports RFC 3339.
 	parsedDate, err := time.Parse("02-01-2006", aux.Date)
+	if err != nil {
+		parsedDate, err = time.Parse(time.RFC3339, aux.Date)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to parse date %s: %w", aux.Date, err)
 	}
@@ -65,7 +85,10 @@ func (d *Document) SaveToCSV(filename string) error {
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	headers := []string{"Date", "Description", "Amount", "Currency"}
+	headers := []string{
+		"Date", "Description", "Amount", "Currency", "Reference",
+		"Original Amount", "Original Currency", "Rate", "Rate Date", "Rate Provider",
+	}
 	if err := writer.Write(headers); err != nil {
 		return fmt.Errorf("failed to write headers: %w", err)
 	}
@@ -74,8 +97,20 @@ func (d *Document) SaveToCSV(filename string) error {
 		record := []string{
 			t.Date.Format("2006-01-02"),
 			t.Description,
-			fmt.Sprintf("%.2f", t.Amount),
+			formatAmount(t.Amount, t.Currency),
 			string(t.Currency),
+			t.Reference,
+		}
+		if c := t.Conversion; c != nil {
+			record = append(record,
+				formatAmount(c.OriginalAmount, c.OriginalCurrency),
+				string(c.OriginalCurrency),
+				strconv.FormatFloat(c.Rate, 'f', -1, 64),
+				c.RateDate.Format("2006-01-02"),
+				c.RateProvider,
+			)
+		} else {
+			record = append(record, "", "", "", "", "")
 		}
 		if err := writer.Write(record); err != nil {
 			return fmt.Errorf("failed to write record: %w", err)
@@ -84,3 +119,25 @@ func (d *Document) SaveToCSV(filename string) error {
 
 	return nil
 }
+
+// formatAmount pads an amount to the currency's minor units. Amounts
+// already have that scale unless built by hand, and one too large to pad
+// is written as it is.
+func formatAmount(m Money, c Currency) string {
+	rounded, err := m.RoundTo(c, HalfEven)
+	if err != nil {
+		return m.String()
+	}
+	return rounded.String()
+}
+
+func (d *Document) SaveToJSON(filename string) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidMoney  = errors.New("invalid money amount")
+	ErrMoneyOverflow = errors.New("money amount out of range")
+)
+
+// RoundingMode decides which way an amount exactly halfway between two
+// minor units goes.
+type RoundingMode int
+
+const (
+	// HalfEven rounds halves to the even neighbour (banker's rounding), so
+	// that rounding errors cancel out over many transactions.
+	HalfEven RoundingMode = iota
+	// HalfUp rounds halves away from zero, as on most receipts.
+	HalfUp
+)
+
+func (r RoundingMode) String() string {
+	switch r {
+	case HalfEven:
+		return "half-even"
+	case HalfUp:
+		return "half-up"
+	}
+	return fmt.Sprintf("RoundingMode(%d)", int(r))
+}
+
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "half-even", "halfeven", "bankers":
+		return HalfEven, nil
+	case "half-up", "halfup":
+		return HalfUp, nil
+	}
+	return 0, fmt.Errorf("unknown rounding mode %q (want half-even or half-up)", s)
+}
+
+// Money is a fixed-point decimal amount of units × 10^-scale. Amounts on a
+// transaction have the scale of its currency's minor units.
+type Money struct {
+	units int64
+	scale int
+}
+
+func NewMoney(units int64, scale int) Money {
+	return Money{
+		units: units,
+		scale: scale,
+	}
+}
+
+// ParseMoney parses a plain decimal such as "-1234.56". Use
+// util.ParseAmount first for amounts written with grouping or symbols.
+func ParseMoney(s string) (Money, error) {
+	invalid := fmt.Errorf("%q: %w", s, ErrInvalidMoney)
+
+	v := strings.TrimSpace(s)
+	negative := strings.HasPrefix(v, "-")
+	if negative || strings.HasPrefix(v, "+") {
+		v = v[1:]
+	}
+	whole, frac, _ := strings.Cut(v, ".")
+	digits := whole + frac
+	if digits == "" || strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return Money{}, invalid
+	}
+	units, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%q: %w", s, ErrMoneyOverflow)
+	}
+	if negative {
+		units = -units
+	}
+	return Money{
+		units: units,
+		scale: len(frac),
+	}, nil
+}
+
+// MustParseMoney is ParseMoney for constants. It panics on invalid input.
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m Money) Units() int64 {
+	return m.units
+}
+
+func (m Money) Scale() int {
+	return m.scale
+}
+
+// String formats the amount as a plain decimal with all of its digits,
+// e.g. "-45.50".
+func (m Money) String() string {
+	digits := strconv.FormatInt(m.units, 10)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if m.scale <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= m.scale {
+		digits = strings.Repeat("0", m.scale-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-m.scale] + "." + digits[len(digits)-m.scale:]
+}
+
+// Float64 returns the nearest float64, for display and statistics only.
+func (m Money) Float64() float64 {
+	f, _ := m.rat().Float64()
+	return f
+}
+
+func (m Money) Sign() int {
+	switch {
+	case m.units < 0:
+		return -1
+	case m.units > 0:
+		return 1
+	}
+	return 0
+}
+
+func (m Money) IsZero() bool {
+	return m.units == 0
+}
+
+func (m Money) Neg() Money {
+	return Money{
+		units: -m.units,
+		scale: m.scale,
+	}
+}
+
+func (m Money) Abs() Money {
+	if m.units < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+// Add returns the exact sum, at the larger of the two scales. It fails
+// with ErrMoneyOverflow when the sum does not fit.
+func (m Money) Add(o Money) (Money, error) {
+	scale := m.scale
+	if o.scale > scale {
+		scale = o.scale
+	}
+	return fromRat(new(big.Rat).Add(m.rat(), o.rat()), scale, HalfEven)
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	return m.Add(o.Neg())
+}
+
+// Cmp compares the values of m and o regardless of scale.
+func (m Money) Cmp(o Money) int {
+	return m.rat().Cmp(o.rat())
+}
+
+func (m Money) Equal(o Money) bool {
+	return m.Cmp(o) == 0
+}
+
+// Round returns the amount with exactly scale decimals. It fails with
+// ErrMoneyOverflow when more decimals make the amount too large.
+func (m Money) Round(scale int, mode RoundingMode) (Money, error) {
+	if scale == m.scale {
+		return m, nil
+	}
+	return fromRat(m.rat(), scale, mode)
+}
+
+// RoundTo rounds to the minor units of the currency.
+func (m Money) RoundTo(c Currency, mode RoundingMode) (Money, error) {
+	return m.Round(c.MinorUnits(), mode)
+}
+
+// Mul multiplies by an exchange rate and rounds the product to scale. The
+// rate is taken as the shortest decimal that reads back as the same float,
+// i.e. the number the rate provider sent.
+func (m Money) Mul(rate float64, scale int, mode RoundingMode) (Money, error) {
+	r, err := rateRat(rate)
+	if err != nil {
+		return Money{}, err
+	}
+	return fromRat(r.Mul(r, m.rat()), scale, mode)
+}
+
+// Div divides by an exchange rate and rounds the quotient to scale.
+func (m Money) Div(rate float64, scale int, mode RoundingMode) (Money, error) {
+	r, err := rateRat(rate)
+	if err != nil {
+		return Money{}, err
+	}
+	if r.Sign() == 0 {
+		return Money{}, errors.New("division by a zero rate")
+	}
+	return fromRat(r.Quo(m.rat(), r), scale, mode)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	parsed, err := ParseMoney(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m Money) rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(m.units), pow10(m.scale))
+}
+
+func rateRat(rate float64) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'g', -1, 64))
+	if !ok {
+		return nil, fmt.Errorf("invalid rate %v", rate)
+	}
+	return r, nil
+}
+
+func fromRat(r *big.Rat, scale int, mode RoundingMode) (Money, error) {
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(pow10(scale)))
+	num, den := scaled.Num(), scaled.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	switch c := twice.Cmp(den); {
+	case c > 0, c == 0 && (mode == HalfUp || q.Bit(0) == 1):
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+
+	if !q.IsInt64() {
+		return Money{}, fmt.Errorf("%s: %w", r.FloatString(scale), ErrMoneyOverflow)
+	}
+	return Money{
+		units: q.Int64(),
+		scale: scale,
+	}, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,64 @@
+package documenttest
+
+import (
+	"testing"
+
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrencyNormalize(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		input   string
+		want    document.Currency
+		wantErr error
+	}{
+		{input: "SEK", want: document.SEK},
+		{input: " eur ", want: document.EUR},
+		{input: "752", want: document.SEK},
+		{input: "008", want: "ALL"},
+		{input: "€", want: document.EUR},
+		{input: "kr", want: document.SEK},
+		{input: "Kr", want: document.SEK},
+		{input: "$", want: document.USD},
+		{input: "zł", want: "PLN"},
+		{input: "NZ$", want: "NZD"},
+		{input: "SEKK", wantErr: document.ErrUnknownCurrency},
+		{input: "XYZ", wantErr: document.ErrUnknownCurrency},
+		{input: "kr.", wantErr: document.ErrUnknownCurrency},
+		{input: "", wantErr: document.ErrUnknownCurrency},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+			got, err := document.Currency(tc.input).Normalize()
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCurrencyInfo(t *testing.T) {
+	t.Parallel()
+	info, ok := document.SEK.Info()
+	require.True(t, ok)
+	require.Equal(t, "752", info.Numeric)
+	require.Equal(t, "Swedish Krona", info.Name)
+	require.Equal(t, 2, info.MinorUnits)
+
+	require.True(t, document.Currency("JPY").Valid())
+	require.False(t, document.Currency("jpy").Valid())
+	require.Equal(t, 0, document.Currency("jpy").MinorUnits())
+	require.Equal(t, 3, document.Currency("KWD").MinorUnits())
+
+	seen := map[document.Currency]bool{}
+	numerics := map[string]bool{}
+	for _, c := range document.Currencies() {
+		require.False(t, seen[c.Code], "duplicate code %s", c.Code)
+		require.False(t, numerics[c.Numeric], "duplicate numeric code %s", c.Numeric)
+		require.Len(t, c.Numeric, 3)
+		seen[c.Code], numerics[c.Numeric] = true, true
+	}
+}
@@ -0,0 +1,71 @@
+package documenttest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/stretchr/testify/require"
+)
+
+func convertedDocument() *document.Document {
+	date := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	return &document.Document{
+		Transactions: []document.Transaction{
+			{
+				Description: "Coffee",
+				Date:        date,
+				Amount:      document.MustParseMoney("-4.50"),
+				Currency:    document.EUR,
+				Conversion: &document.Conversion{
+					OriginalAmount:   document.MustParseMoney("-50.00"),
+					OriginalCurrency: document.SEK,
+					Rate:             0.09,
+					RateDate:         date.AddDate(0, 0, -1),
+					RateProvider:     "exchangeapi",
+				},
+			},
+			{
+				Description: "Rent",
+				Date:        date,
+				Amount:      document.MustParseMoney("-800.00"),
+				Currency:    document.EUR,
+			},
+		},
+	}
+}
+
+func TestSaveToCSVConversion(t *testing.T) {
+	t.Parallel()
+	filename := filepath.Join(t.TempDir(), "out.csv")
+	require.NoError(t, convertedDocument().SaveToCSV(filename))
+
+	file, err := os.Open(filename)
+	require.NoError(t, err)
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	require.NoError(t, err)
+
+	require.Equal(t, [][]string{
+		{"Date", "Description", "Amount", "Currency", "Reference", "Original Amount", "Original Currency", "Rate", "Rate Date", "Rate Provider"},
+		{"2025-01-02", "Coffee", "-4.50", "EUR", "", "-50.00", "SEK", "0.09", "2025-01-01", "exchangeapi"},
+		{"2025-01-02", "Rent", "-800.00", "EUR", "", "", "", "", "", ""},
+	}, records)
+}
+
+func TestSaveToJSONRoundTrip(t *testing.T) {
+	t.Parallel()
+	filename := filepath.Join(t.TempDir(), "out.json")
+	want := convertedDocument()
+	require.NoError(t, want.SaveToJSON(filename))
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	got := &document.Document{}
+	require.NoError(t, json.Unmarshal(data, got))
+	require.Equal(t, want, got)
+}
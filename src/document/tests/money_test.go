@@ -0,0 +1,113 @@
+package documenttest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{input: "1234.56", want: "1234.56"},
+		{input: "-0.5", want: "-0.5"},
+		{input: "+12", want: "12"},
+		{input: ".05", want: "0.05"},
+		{input: "12.", want: "12"},
+		{input: "1,234.56", wantErr: document.ErrInvalidMoney},
+		{input: "--5", wantErr: document.ErrInvalidMoney},
+		{input: "", wantErr: document.ErrInvalidMoney},
+		{input: "99999999999999999999", wantErr: document.ErrMoneyOverflow},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+			got, err := document.ParseMoney(tc.input)
+			require.ErrorIs(t, err, tc.wantErr)
+			if err == nil {
+				require.Equal(t, tc.want, got.String())
+			}
+		})
+	}
+}
+
+func TestMoneyRound(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		input string
+		scale int
+		mode  document.RoundingMode
+		want  string
+	}{
+		{input: "2.345", scale: 2, mode: document.HalfEven, want: "2.34"},
+		{input: "2.345", scale: 2, mode: document.HalfUp, want: "2.35"},
+		{input: "2.355", scale: 2, mode: document.HalfEven, want: "2.36"},
+		{input: "-2.345", scale: 2, mode: document.HalfEven, want: "-2.34"},
+		{input: "-2.345", scale: 2, mode: document.HalfUp, want: "-2.35"},
+		{input: "2.3451", scale: 2, mode: document.HalfEven, want: "2.35"},
+		{input: "12.5", scale: 0, mode: document.HalfEven, want: "12"},
+		{input: "13.5", scale: 0, mode: document.HalfEven, want: "14"},
+		{input: "12", scale: 2, mode: document.HalfEven, want: "12.00"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.input+"/"+tc.mode.String(), func(t *testing.T) {
+			t.Parallel()
+			got, err := document.MustParseMoney(tc.input).Round(tc.scale, tc.mode)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got.String())
+			require.Equal(t, tc.scale, got.Scale())
+		})
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	t.Parallel()
+	// 0.1 + 0.2 is exact, unlike with float64.
+	sum, err := document.MustParseMoney("0.1").Add(document.MustParseMoney("0.20"))
+	require.NoError(t, err)
+	require.Equal(t, "0.30", sum.String())
+	require.True(t, sum.Equal(document.MustParseMoney("0.3")))
+	diff, err := document.MustParseMoney("0.1").Sub(document.MustParseMoney("0.20"))
+	require.NoError(t, err)
+	require.Equal(t, "-0.10", diff.String())
+
+	// Results that do not fit fail instead of wrapping or panicking.
+	_, err = document.MustParseMoney("9000000000000000000").Add(document.MustParseMoney("0.5"))
+	require.ErrorIs(t, err, document.ErrMoneyOverflow)
+	_, err = document.MustParseMoney("9000000000000000000").RoundTo(document.SEK, document.HalfEven)
+	require.ErrorIs(t, err, document.ErrMoneyOverflow)
+
+	converted, err := document.MustParseMoney("1124.23").Div(11.24233239, document.EUR.MinorUnits(), document.HalfEven)
+	require.NoError(t, err)
+	require.Equal(t, "100.00", converted.String())
+
+	converted, err = document.MustParseMoney("100.00").Mul(150.125, document.Currency("JPY").MinorUnits(), document.HalfUp)
+	require.NoError(t, err)
+	require.Equal(t, "15013", converted.String())
+
+	_, err = document.MustParseMoney("1").Div(0, 2, document.HalfEven)
+	require.Error(t, err)
+}
+
+func TestMoneyJSON(t *testing.T) {
+	t.Parallel()
+	data, err := json.Marshal(struct {
+		Amount document.Money `json:"amount"`
+	}{document.MustParseMoney("-45.50")})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"amount": -45.50}`, string(data))
+
+	var got struct {
+		Amount document.Money `json:"amount"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"amount": "12.30"}`), &got))
+	require.Equal(t, document.MustParseMoney("12.30"), got.Amount)
+}
@@ -0,0 +1,224 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/document"
+)
+
+var ErrInvalidCamt = errors.New("invalid camt statement")
+
+// Camt imports ISO 20022 camt.053 (end-of-day statement) and camt.052
+// (intraday account report) XML files. Pending entries are left out, as
+// they come back booked in a later report.
+type Camt struct{}
+
+type CamtConfig struct {
+	// UseValueDate uses ValDt instead of BookgDt as the transaction date.
+	UseValueDate bool
+}
+
+func NewCamt() *Camt {
+	return &Camt{}
+}
+
+// Element names are matched without namespace so that every camt.05x
+// version (001.02, 001.08, ...) is accepted.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+	Reports    []camtStatement `xml:"BkToCstmrAcctRpt>Rpt"`
+}
+
+type camtStatement struct {
+	Currency string      `xml:"Acct>Ccy"`
+	Entries  []camtEntry `xml:"Ntry"`
+}
+
+type camtEntry struct {
+	NtryRef      string        `xml:"NtryRef"`
+	Amount       camtAmount    `xml:"Amt"`
+	CdtDbtInd    string        `xml:"CdtDbtInd"`
+	Status       camtStatus    `xml:"Sts"`
+	BookingDate  camtDate      `xml:"BookgDt"`
+	ValueDate    camtDate      `xml:"ValDt"`
+	AcctSvcrRef  string        `xml:"AcctSvcrRef"`
+	AddtlNtryInf string        `xml:"AddtlNtryInf"`
+	Details      []camtDetails `xml:"NtryDtls>TxDtls"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+// camtStatus is a bare code up to version 001.06 and a Cd element after.
+type camtStatus struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+func (s camtStatus) code() string {
+	if s.Code != "" {
+		return strings.TrimSpace(s.Code)
+	}
+	return strings.TrimSpace(s.Value)
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtDetails struct {
+	EndToEndId   string   `xml:"Refs>EndToEndId"`
+	AcctSvcrRef  string   `xml:"Refs>AcctSvcrRef"`
+	Unstructured []string `xml:"RmtInf>Ustrd"`
+	Structured   []string `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	AddtlTxInf   string   `xml:"AddtlTxInf"`
+}
+
+func (c *Camt) Import(filePath string, config *CamtConfig) (*document.Document, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open camt file: %w", err)
+	}
+	if config == nil {
+		config = &CamtConfig{}
+	}
+
+	var doc camtDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%v: %w", err, ErrInvalidCamt)
+	}
+
+	var transactions []document.Transaction
+	pending := 0
+	for _, stmt := range append(doc.Statements, doc.Reports...) {
+		for _, entry := range stmt.Entries {
+			if entry.Status.code() == "PDNG" {
+				pending++
+				continue
+			}
+			t, err := entry.toTransaction(stmt.Currency, config.UseValueDate)
+			if err != nil {
+				return nil, err
+			}
+			transactions = append(transactions, t)
+		}
+	}
+	if len(transactions) == 0 && pending > 0 {
+		return nil, fmt.Errorf("all %d entries are pending: %w", pending, ErrInvalidCamt)
+	}
+	if len(transactions) == 0 {
+		return nil, fmt.Errorf("no entries found: %w", ErrInvalidCamt)
+	}
+	return &document.Document{
+		Transactions: transactions,
+	}, nil
+}
+
+func (e *camtEntry) toTransaction(accountCurrency string, useValueDate bool) (document.Transaction, error) {
+	primary, secondary := e.BookingDate, e.ValueDate
+	if useValueDate {
+		primary, secondary = secondary, primary
+	}
+	date, err := primary.parse()
+	if err != nil {
+		date, err = secondary.parse()
+	}
+	if err != nil {
+		return document.Transaction{}, fmt.Errorf("entry %s has no usable date: %w", e.reference(), ErrInvalidCamt)
+	}
+
+	amount, err := document.ParseMoney(e.Amount.Value)
+	if err != nil {
+		return document.Transaction{}, fmt.Errorf("failed to parse amount %q: %w", e.Amount.Value, ErrInvalidCamt)
+	}
+	switch e.CdtDbtInd {
+	case "DBIT":
+		amount = amount.Neg()
+	case "CRDT":
+	default:
+		return document.Transaction{}, fmt.Errorf("unknown credit/debit indicator %q: %w", e.CdtDbtInd, ErrInvalidCamt)
+	}
+
+	currency := e.Amount.Currency
+	if currency == "" {
+		currency = accountCurrency
+	}
+	if currency == "" {
+		return document.Transaction{}, fmt.Errorf("entry %s has no currency: %w", e.reference(), ErrInvalidCamt)
+	}
+
+	rounded, err := amount.RoundTo(document.Currency(currency), document.HalfEven)
+	if err != nil {
+		return document.Transaction{}, fmt.Errorf("entry %s: %w", e.reference(), err)
+	}
+
+	return document.Transaction{
+		Description: e.description(),
+		Date:        date,
+		Amount:      rounded,
+		Currency:    document.Currency(currency),
+		Reference:   e.reference(),
+	}, nil
+}
+
+func (e *camtEntry) description() string {
+	var parts []string
+	for _, d := range e.Details {
+		parts = append(parts, d.Unstructured...)
+		parts = append(parts, d.Structured...)
+	}
+	if len(parts) == 0 {
+		for _, d := range e.Details {
+			if d.AddtlTxInf != "" {
+				parts = append(parts, d.AddtlTxInf)
+			}
+		}
+	}
+	if len(parts) == 0 && e.AddtlNtryInf != "" {
+		parts = append(parts, e.AddtlNtryInf)
+	}
+	for i := range parts {
+		parts[i] = strings.Join(strings.Fields(parts[i]), " ")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (e *camtEntry) reference() string {
+	if e.AcctSvcrRef != "" {
+		return e.AcctSvcrRef
+	}
+	if e.NtryRef != "" {
+		return e.NtryRef
+	}
+	for _, d := range e.Details {
+		if d.AcctSvcrRef != "" {
+			return d.AcctSvcrRef
+		}
+		if d.EndToEndId != "" && d.EndToEndId != "NOTPROVIDED" {
+			return d.EndToEndId
+		}
+	}
+	return ""
+}
+
+func (d camtDate) parse() (time.Time, error) {
+	if d.Date != "" {
+		return time.Parse("2006-01-02", strings.TrimSpace(d.Date))
+	}
+	if d.DateTime != "" {
+		// Keep the calendar date as written by the bank, ignoring the offset.
+		s := strings.TrimSpace(d.DateTime)
+		if len(s) >= 10 {
+			return time.Parse("2006-01-02", s[:10])
+		}
+	}
+	return time.Time{}, errors.New("no date")
+}
@@ -1,8 +1,11 @@
 package importer
 
 import (
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/lazeratops/optimusdime/src/document"
@@ -23,23 +26,37 @@ func NewCsv(parser *parser.Parser) *Csv {
 	}
 }
 
-func (c *Csv) Import(filePath string, config *CsvConfig) (*document.Document, error) {
+func (c *Csv) Import(ctx context.Context, filePath string, config *CsvConfig) (*document.Document, *parser.Report, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open CSV: %w", err)
+		return nil, nil, fmt.Errorf("failed to open CSV: %w", err)
 	}
 	defer file.Close()
 	reader := csv.NewReader(file)
+	// Preamble and footer rows are usually shorter than transactions; the
+	// parser sorts them out.
+	reader.FieldsPerRecord = -1
 	if config != nil {
 		reader.Comma = config.delimiter
 	}
-	// Read all records
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	// The reader skips blank lines, so keep each record's line for the
+	// report.
+	var records [][]string
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
 	}
 	if len(records) == 0 {
-		return nil, fmt.Errorf("csv file is empty")
+		return nil, nil, fmt.Errorf("csv file is empty")
 	}
-	return c.parser.Parse(records)
+	return c.parser.ParseRows(ctx, records, lines)
 }
@@ -0,0 +1,335 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/document"
+)
+
+var (
+	ErrInvalidMt940    = errors.New("invalid MT940 statement")
+	ErrBalanceMismatch = errors.New("closing balance does not match statement lines")
+)
+
+// Mt940 imports SWIFT MT940 customer statements.
+type Mt940 struct{}
+
+func NewMt940() *Mt940 {
+	return &Mt940{}
+}
+
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+type mt940Balance struct {
+	amount   document.Money
+	currency string
+}
+
+type mt940Statement struct {
+	reference    string
+	opening      *mt940Balance
+	closing      *mt940Balance
+	transactions []document.Transaction
+}
+
+func (m *Mt940) Import(filePath string) (*document.Document, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open MT940: %w", err)
+	}
+	defer file.Close()
+
+	fields, err := readMt940Fields(bufio.NewScanner(file))
+	if err != nil {
+		return nil, err
+	}
+
+	statements, err := parseMt940(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions []document.Transaction
+	for _, s := range statements {
+		if err := s.verify(); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, s.transactions...)
+	}
+	if len(transactions) == 0 {
+		return nil, fmt.Errorf("no statement lines found: %w", ErrInvalidMt940)
+	}
+	return &document.Document{
+		Transactions: transactions,
+	}, nil
+}
+
+// readMt940Fields splits the file into :tag:value fields, joining
+// continuation lines and dropping the SWIFT envelope blocks.
+func readMt940Fields(scanner *bufio.Scanner) ([]mt940Field, error) {
+	var fields []mt940Field
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r ")
+		if i := strings.Index(line, "{4:"); i >= 0 {
+			line = line[i+3:]
+		}
+		if line == "" || line == "-" || line == "-}" || strings.HasPrefix(line, "{") {
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			if end := strings.Index(line[1:], ":"); end > 0 {
+				fields = append(fields, mt940Field{
+					tag:   line[1 : end+1],
+					value: line[end+2:],
+				})
+				continue
+			}
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("unexpected line %q: %w", line, ErrInvalidMt940)
+		}
+		fields[len(fields)-1].value += "\n" + line
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read MT940: %w", err)
+	}
+	return fields, nil
+}
+
+func parseMt940(fields []mt940Field) ([]*mt940Statement, error) {
+	var statements []*mt940Statement
+	var current *mt940Statement
+	var pending *document.Transaction
+
+	flush := func() {
+		if pending != nil {
+			current.transactions = append(current.transactions, *pending)
+			pending = nil
+		}
+	}
+
+	for _, f := range fields {
+		if f.tag == "20" {
+			if current != nil {
+				flush()
+			}
+			current = &mt940Statement{reference: f.value}
+			statements = append(statements, current)
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("field :%s: before :20:: %w", f.tag, ErrInvalidMt940)
+		}
+
+		switch f.tag {
+		case "60F", "60M":
+			b, err := parseMt940Balance(f.value)
+			if err != nil {
+				return nil, err
+			}
+			current.opening = b
+		case "61":
+			flush()
+			if current.opening == nil {
+				return nil, fmt.Errorf("statement %s: :61: before opening balance: %w", current.reference, ErrInvalidMt940)
+			}
+			t, err := parseMt940Line(f.value, current.opening.currency)
+			if err != nil {
+				return nil, err
+			}
+			pending = t
+		case "86":
+			if pending != nil {
+				if d := parseMt940Narrative(f.value); d != "" {
+					pending.Description = d
+				}
+			}
+		case "62F", "62M":
+			flush()
+			b, err := parseMt940Balance(f.value)
+			if err != nil {
+				return nil, err
+			}
+			current.closing = b
+		}
+	}
+	if current != nil {
+		flush()
+	}
+	return statements, nil
+}
+
+func (s *mt940Statement) verify() error {
+	if s.opening == nil || s.closing == nil {
+		return fmt.Errorf("statement %s is missing its opening or closing balance: %w", s.reference, ErrInvalidMt940)
+	}
+	if s.opening.currency != s.closing.currency {
+		return fmt.Errorf("statement %s opens in %s but closes in %s: %w", s.reference, s.opening.currency, s.closing.currency, ErrInvalidMt940)
+	}
+	sum := s.opening.amount
+	for _, t := range s.transactions {
+		var err error
+		sum, err = sum.Add(t.Amount)
+		if err != nil {
+			return fmt.Errorf("statement %s: %w", s.reference, err)
+		}
+	}
+	if !sum.Equal(s.closing.amount) {
+		return fmt.Errorf("statement %s: opening %s plus lines gives %s, closing is %s: %w",
+			s.reference, s.opening.amount, sum, s.closing.amount, ErrBalanceMismatch)
+	}
+	return nil
+}
+
+// parseMt940Balance parses 1!a6!n3!a15d, e.g. C240131SEK1234,56.
+func parseMt940Balance(v string) (*mt940Balance, error) {
+	v = strings.TrimSpace(v)
+	if len(v) < 11 {
+		return nil, fmt.Errorf("malformed balance %q: %w", v, ErrInvalidMt940)
+	}
+	amount, err := parseMt940Amount(v[10:])
+	if err != nil {
+		return nil, err
+	}
+	switch v[0] {
+	case 'D':
+		amount = amount.Neg()
+	case 'C':
+	default:
+		return nil, fmt.Errorf("malformed balance mark in %q: %w", v, ErrInvalidMt940)
+	}
+	return &mt940Balance{
+		amount:   amount,
+		currency: v[7:10],
+	}, nil
+}
+
+// parseMt940Line parses a :61: statement line:
+// 6!n[4!n]2a[1!a]15d1!a3!c16x[//16x][34x]
+func parseMt940Line(v string, currency string) (*document.Transaction, error) {
+	first, supplementary, _ := strings.Cut(v, "\n")
+	malformed := fmt.Errorf("malformed statement line %q: %w", first, ErrInvalidMt940)
+
+	if len(first) < 6 {
+		return nil, malformed
+	}
+	date, err := time.Parse("060102", first[:6])
+	if err != nil {
+		return nil, malformed
+	}
+	rest := first[6:]
+	// Optional MMDD entry date.
+	if len(rest) >= 4 && isDigits(rest[:4]) {
+		rest = rest[4:]
+	}
+
+	var sign int
+	switch {
+	case strings.HasPrefix(rest, "RC"):
+		sign, rest = -1, rest[2:]
+	case strings.HasPrefix(rest, "RD"):
+		sign, rest = 1, rest[2:]
+	case strings.HasPrefix(rest, "C"):
+		sign, rest = 1, rest[1:]
+	case strings.HasPrefix(rest, "D"):
+		sign, rest = -1, rest[1:]
+	default:
+		return nil, malformed
+	}
+	// Optional funds code.
+	if len(rest) > 0 && (rest[0] < '0' || rest[0] > '9') {
+		rest = rest[1:]
+	}
+
+	end := strings.IndexFunc(rest, func(r rune) bool {
+		return (r < '0' || r > '9') && r != ','
+	})
+	if end <= 0 {
+		return nil, malformed
+	}
+	amount, err := parseMt940Amount(rest[:end])
+	if err != nil {
+		return nil, err
+	}
+	rest = rest[end:]
+	if sign < 0 {
+		amount = amount.Neg()
+	}
+
+	// Transaction type identification code, e.g. NTRF.
+	if len(rest) < 4 {
+		return nil, malformed
+	}
+	rest = rest[4:]
+	customerRef, bankRef, _ := strings.Cut(rest, "//")
+
+	reference := strings.TrimSpace(bankRef)
+	if customerRef != "" && customerRef != "NONREF" {
+		reference = customerRef
+	}
+
+	rounded, err := amount.RoundTo(document.Currency(currency), document.HalfEven)
+	if err != nil {
+		return nil, err
+	}
+
+	return &document.Transaction{
+		Description: strings.TrimSpace(supplementary),
+		Date:        date,
+		Amount:      rounded,
+		Currency:    document.Currency(currency),
+		Reference:   reference,
+	}, nil
+}
+
+// parseMt940Narrative flattens a :86: field. Structured narratives that use
+// ?nn subfields (common with German banks) keep only the posting text,
+// remittance lines and counterparty name.
+func parseMt940Narrative(v string) string {
+	if !strings.Contains(v, "?2") {
+		return strings.Join(strings.Fields(v), " ")
+	}
+	v = strings.ReplaceAll(v, "\n", "")
+
+	var parts []string
+	for _, sub := range strings.Split(v, "?")[1:] {
+		if len(sub) < 2 {
+			continue
+		}
+		code, text := sub[:2], strings.TrimSpace(sub[2:])
+		if text == "" {
+			continue
+		}
+		switch {
+		case code == "00", code[0] == '2', code == "32", code == "33", code[0] == '6':
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func parseMt940Amount(s string) (document.Money, error) {
+	s = strings.TrimSpace(s)
+	amount, err := document.ParseMoney(strings.Replace(s, ",", ".", 1))
+	if err != nil {
+		return document.Money{}, fmt.Errorf("malformed amount %q: %w", s, ErrInvalidMt940)
+	}
+	return amount, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,203 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/document"
+)
+
+var ErrInvalidOfx = errors.New("invalid OFX statement")
+
+// Ofx imports OFX/QFX statements. Both the SGML (v1) and XML (v2) flavours
+// are supported. No parser is needed since OFX already carries typed values.
+type Ofx struct{}
+
+type OfxConfig struct {
+	// DefaultCurrency is used when the statement has no CURDEF.
+	DefaultCurrency document.Currency
+}
+
+func NewOfx() *Ofx {
+	return &Ofx{}
+}
+
+func (o *Ofx) Import(filePath string, config *OfxConfig) (*document.Document, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OFX: %w", err)
+	}
+	var defaultCurrency document.Currency
+	if config != nil {
+		defaultCurrency = config.DefaultCurrency
+	}
+	transactions, err := parseOfx(string(data), defaultCurrency)
+	if err != nil {
+		return nil, err
+	}
+	if len(transactions) == 0 {
+		return nil, fmt.Errorf("OFX file has no transactions")
+	}
+	return &document.Document{
+		Transactions: transactions,
+	}, nil
+}
+
+type ofxTransaction struct {
+	fields   map[string]string
+	currency string
+}
+
+func parseOfx(content string, defaultCurrency document.Currency) ([]document.Transaction, error) {
+	start := strings.Index(strings.ToUpper(content), "<OFX>")
+	if start < 0 {
+		return nil, fmt.Errorf("no <OFX> element found: %w", ErrInvalidOfx)
+	}
+	content = content[start:]
+
+	var transactions []document.Transaction
+	var current *ofxTransaction
+	var path []string
+	curdef := string(defaultCurrency)
+
+	for len(content) > 0 {
+		open := strings.IndexByte(content, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(content[open:], '>')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated tag: %w", ErrInvalidOfx)
+		}
+		tag := strings.ToUpper(strings.TrimSpace(content[open+1 : open+end]))
+		content = content[open+end+1:]
+
+		// Skip processing instructions and comments in OFX v2 files.
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+
+		if strings.HasPrefix(tag, "/") {
+			name := tag[1:]
+			// Closing tags of leaf elements are optional in SGML, so only
+			// unwind the aggregate stack when the tag is actually open.
+			for i := len(path) - 1; i >= 0; i-- {
+				if path[i] == name {
+					path = path[:i]
+					break
+				}
+			}
+			if name == "STMTTRN" && current != nil {
+				t, err := current.toTransaction(curdef)
+				if err != nil {
+					return nil, err
+				}
+				transactions = append(transactions, t)
+				current = nil
+			}
+			continue
+		}
+
+		value := content
+		if next := strings.IndexByte(content, '<'); next >= 0 {
+			value = content[:next]
+		}
+		value = strings.TrimSpace(value)
+
+		if value == "" {
+			// Aggregate element.
+			path = append(path, tag)
+			if tag == "STMTTRN" {
+				current = &ofxTransaction{fields: map[string]string{}}
+			}
+			continue
+		}
+
+		value = unescapeOfx(value)
+		switch {
+		case tag == "CURDEF":
+			curdef = value
+		case current != nil && tag == "CURSYM" && len(path) > 0 && path[len(path)-1] == "CURRENCY":
+			// Amounts of this transaction are in a currency other than CURDEF.
+			current.currency = value
+		case current != nil:
+			current.fields[tag] = value
+		}
+	}
+
+	return transactions, nil
+}
+
+func (t *ofxTransaction) toTransaction(curdef string) (document.Transaction, error) {
+	date, err := parseOfxDate(t.fields["DTPOSTED"])
+	if err != nil {
+		return document.Transaction{}, err
+	}
+
+	rawAmount := strings.ReplaceAll(t.fields["TRNAMT"], ",", ".")
+	amount, err := document.ParseMoney(rawAmount)
+	if err != nil {
+		return document.Transaction{}, fmt.Errorf("failed to parse amount %q: %w", t.fields["TRNAMT"], ErrInvalidOfx)
+	}
+
+	currency := curdef
+	if t.currency != "" {
+		currency = t.currency
+	}
+	if currency == "" {
+		return document.Transaction{}, fmt.Errorf("no currency for transaction %s: %w", t.fields["FITID"], ErrInvalidOfx)
+	}
+
+	rounded, err := amount.RoundTo(document.Currency(currency), document.HalfEven)
+	if err != nil {
+		return document.Transaction{}, fmt.Errorf("transaction %s: %w", t.fields["FITID"], err)
+	}
+
+	return document.Transaction{
+		Description: ofxDescription(t.fields["NAME"], t.fields["MEMO"]),
+		Date:        date,
+		Amount:      rounded,
+		Currency:    document.Currency(strings.ToUpper(currency)),
+		Reference:   t.fields["FITID"],
+	}, nil
+}
+
+func ofxDescription(name, memo string) string {
+	switch {
+	case name == "":
+		return memo
+	case memo == "" || strings.Contains(name, memo):
+		return name
+	default:
+		return name + " " + memo
+	}
+}
+
+// parseOfxDate parses OFX datetimes such as 20240130, 20240130120000 and
+// 20240130120000.000[-5:EST]. Only the calendar date is kept.
+func parseOfxDate(s string) (time.Time, error) {
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("failed to parse date %q: %w", s, ErrInvalidOfx)
+	}
+	date, err := time.Parse("20060102", s[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse date %q: %w", s, ErrInvalidOfx)
+	}
+	return date, nil
+}
+
+var ofxEntities = strings.NewReplacer(
+	"&amp;", "&",
+	"&lt;", "<",
+	"&gt;", ">",
+	"&quot;", `"`,
+	"&apos;", "'",
+	"&nbsp;", " ",
+)
+
+func unescapeOfx(s string) string {
+	return ofxEntities.Replace(s)
+}
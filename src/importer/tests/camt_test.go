@@ -0,0 +1,170 @@
+package importertest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/lazeratops/optimusdime/src/importer"
+	"github.com/stretchr/testify/require"
+)
+
+const camt053 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Id>1</Id>
+      <Acct><Id><IBAN>SE3550000000054910000003</IBAN></Id><Ccy>SEK</Ccy></Acct>
+      <Ntry>
+        <Amt Ccy="SEK">125.50</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2024-03-01</Dt></BookgDt>
+        <ValDt><Dt>2024-02-29</Dt></ValDt>
+        <AcctSvcrRef>REF-1</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <Refs><EndToEndId>E2E-1</EndToEndId></Refs>
+          <RmtInf><Ustrd>Invoice 42</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">10</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <BookgDt><DtTm>2024-03-02T10:15:00+01:00</DtTm></BookgDt>
+        <ValDt><Dt>2024-03-02</Dt></ValDt>
+        <AddtlNtryInf>Refund   from shop</AddtlNtryInf>
+        <NtryDtls><TxDtls><Refs><EndToEndId>E2E-2</EndToEndId></Refs></TxDtls></NtryDtls>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+
+const camt052 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.052.001.08">
+  <BkToCstmrAcctRpt>
+    <Rpt>
+      <Acct><Ccy>SEK</Ccy></Acct>
+      <Ntry>
+        <Amt>99.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <BookgDt><Dt>2024-03-01</Dt></BookgDt>
+        <NtryRef>N-1</NtryRef>
+        <AddtlNtryInf>Swish</AddtlNtryInf>
+      </Ntry>
+      <Ntry>
+        <Amt>450.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts><Cd>PDNG</Cd></Sts>
+        <BookgDt><Dt>2024-03-01</Dt></BookgDt>
+        <NtryRef>N-2</NtryRef>
+        <AddtlNtryInf>Card payment, not yet booked</AddtlNtryInf>
+      </Ntry>
+    </Rpt>
+  </BkToCstmrAcctRpt>
+</Document>`
+
+func TestCamtImport(t *testing.T) {
+	t.Parallel()
+	mustDate := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		require.NoError(t, err)
+		return d
+	}
+
+	cases := []struct {
+		name    string
+		content string
+		config  *importer.CamtConfig
+		wantDoc *document.Document
+		wantErr error
+	}{
+		{
+			name:    "camt.053 booking date",
+			content: camt053,
+			wantDoc: &document.Document{
+				Transactions: []document.Transaction{
+					{
+						Description: "Invoice 42",
+						Date:        mustDate("2024-03-01"),
+						Amount:      document.MustParseMoney("-125.50"),
+						Currency:    document.SEK,
+						Reference:   "REF-1",
+					},
+					{
+						Description: "Refund from shop",
+						Date:        mustDate("2024-03-02"),
+						Amount:      document.MustParseMoney("10.00"),
+						Currency:    document.EUR,
+						Reference:   "E2E-2",
+					},
+				},
+			},
+		},
+		{
+			name:    "camt.053 value date",
+			content: camt053,
+			config:  &importer.CamtConfig{UseValueDate: true},
+			wantDoc: &document.Document{
+				Transactions: []document.Transaction{
+					{
+						Description: "Invoice 42",
+						Date:        mustDate("2024-02-29"),
+						Amount:      document.MustParseMoney("-125.50"),
+						Currency:    document.SEK,
+						Reference:   "REF-1",
+					},
+					{
+						Description: "Refund from shop",
+						Date:        mustDate("2024-03-02"),
+						Amount:      document.MustParseMoney("10.00"),
+						Currency:    document.EUR,
+						Reference:   "E2E-2",
+					},
+				},
+			},
+		},
+		{
+			name:    "camt.052 account currency",
+			content: camt052,
+			wantDoc: &document.Document{
+				Transactions: []document.Transaction{
+					{
+						Description: "Swish",
+						Date:        mustDate("2024-03-01"),
+						Amount:      document.MustParseMoney("99.00"),
+						Currency:    document.SEK,
+						Reference:   "N-1",
+					},
+				},
+			},
+		},
+		{
+			name: "only pending entries",
+			content: `<Document><BkToCstmrAcctRpt><Rpt><Acct><Ccy>SEK</Ccy></Acct>
+<Ntry><Amt>1.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>PDNG</Sts><BookgDt><Dt>2024-03-01</Dt></BookgDt></Ntry>
+</Rpt></BkToCstmrAcctRpt></Document>`,
+			wantErr: importer.ErrInvalidCamt,
+		},
+		{
+			name:    "no entries",
+			content: `<Document><BkToCstmrStmt><Stmt></Stmt></BkToCstmrStmt></Document>`,
+			wantErr: importer.ErrInvalidCamt,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "statement.xml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			gotDoc, gotErr := importer.NewCamt().Import(path, tc.config)
+			require.ErrorIs(t, gotErr, tc.wantErr)
+			if gotErr == nil {
+				require.EqualValues(t, tc.wantDoc, gotDoc)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package importertest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lazeratops/optimusdime/mocks"
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/lazeratops/optimusdime/src/importer"
+	"github.com/lazeratops/optimusdime/src/parser"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCsvImport(t *testing.T) {
+	t.Parallel()
+	// Preamble and footer rows have fewer fields than the transactions.
+	content := `Account statement
+Currency:,SEK
+Date,Description,Amount
+2025-01-02,Rent,"-1 234,50"
+
+2025-01-03,Swish,"250,00"
+Exported by,Nordea Bank
+`
+	path := filepath.Join(t.TempDir(), "statement.csv")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	mockLlm := mocks.NewLlm(t)
+	mockLlm.On("FindElements", mock.Anything, mock.Anything, mock.Anything).Return(map[string]int{
+		"date":        0,
+		"description": 1,
+		"amount":      2,
+	}, nil)
+
+	doc, report, err := importer.NewCsv(parser.NewParser(mockLlm, nil)).Import(context.Background(), path, nil)
+	require.NoError(t, err)
+	require.Len(t, doc.Transactions, 2)
+	require.Equal(t, "-1234.50", doc.Transactions[0].Amount.String())
+	require.Equal(t, document.SEK, doc.Transactions[0].Currency)
+	require.Equal(t, document.CurrencySourceMetadata, doc.Transactions[0].CurrencySource)
+	require.Equal(t, "250.00", doc.Transactions[1].Amount.String())
+	require.Equal(t, 2, report.Transactions)
+	// Rows are numbered by line, counting the blank one the reader skips.
+	require.Equal(t, 7, report.Issues[len(report.Issues)-1].Row)
+	require.Equal(t, parser.RowFooter, report.Issues[len(report.Issues)-1].Kind)
+}
+
+func TestCsvImportNoTransactions(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "statement.csv")
+	require.NoError(t, os.WriteFile(path, []byte("Date,Description,Amount\nExported by,Nordea Bank\n"), 0o644))
+
+	mockLlm := mocks.NewLlm(t)
+	mockLlm.On("FindElements", mock.Anything, mock.Anything, mock.Anything).Return(map[string]int{
+		"date":        0,
+		"description": 1,
+		"amount":      2,
+	}, nil)
+
+	_, _, err := importer.NewCsv(parser.NewParser(mockLlm, nil)).Import(context.Background(), path, nil)
+	require.ErrorIs(t, err, parser.ErrNoTransactions)
+}
@@ -0,0 +1,95 @@
+package importertest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/lazeratops/optimusdime/src/importer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMt940Import(t *testing.T) {
+	t.Parallel()
+	mustDate := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		require.NoError(t, err)
+		return d
+	}
+
+	cases := []struct {
+		name    string
+		content string
+		wantDoc *document.Document
+		wantErr error
+	}{
+		{
+			name: "success",
+			content: `{1:F01BANKSESSAXXX0000000000}{2:O9401200240131BANKSESSAXXX00000000002401311200N}{4:
+:20:STMT-1
+:25:SE3550000000054910000003
+:28C:1/1
+:60F:C240130SEK1000,00
+:61:2401300130D45,50NTRFNONREF//B-1
+:86:ICA Supermarket
+Stockholm
+:61:2401310131C100,NMSCINV-42//B-2
+:86:166?00GUTSCHRIFT?20Invoice 42?21March?32ACME AB
+:62F:C240131SEK1054,50
+-}`,
+			wantDoc: &document.Document{
+				Transactions: []document.Transaction{
+					{
+						Description: "ICA Supermarket Stockholm",
+						Date:        mustDate("2024-01-30"),
+						Amount:      document.MustParseMoney("-45.50"),
+						Currency:    document.SEK,
+						Reference:   "B-1",
+					},
+					{
+						Description: "GUTSCHRIFT Invoice 42 March ACME AB",
+						Date:        mustDate("2024-01-31"),
+						Amount:      document.MustParseMoney("100.00"),
+						Currency:    document.SEK,
+						Reference:   "INV-42",
+					},
+				},
+			},
+		},
+		{
+			name: "balance mismatch",
+			content: `:20:STMT-1
+:60F:C240130EUR10,00
+:61:240130D5,00NTRFNONREF
+:86:Coffee
+:62F:C240130EUR6,00
+`,
+			wantErr: importer.ErrBalanceMismatch,
+		},
+		{
+			name: "malformed line",
+			content: `:20:STMT-1
+:60F:C240130EUR10,00
+:61:240130X5,00NTRF
+:62F:C240130EUR10,00
+`,
+			wantErr: importer.ErrInvalidMt940,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "statement.sta")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			gotDoc, gotErr := importer.NewMt940().Import(path)
+			require.ErrorIs(t, gotErr, tc.wantErr)
+			if gotErr == nil {
+				require.EqualValues(t, tc.wantDoc, gotDoc)
+			}
+		})
+	}
+}
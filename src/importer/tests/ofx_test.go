@@ -0,0 +1,150 @@
+package importertest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/lazeratops/optimusdime/src/importer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfxImport(t *testing.T) {
+	t.Parallel()
+	date_20240130, err := time.Parse("2006-01-02", "2024-01-30")
+	require.NoError(t, err)
+	date_20240201, err := time.Parse("2006-01-02", "2024-02-01")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		content string
+		wantDoc *document.Document
+		wantErr error
+	}{
+		{
+			name: "sgml v1",
+			content: `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+CHARSET:1252
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>20240202<LANGUAGE>ENG</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<STMTRS>
+<CURDEF>SEK
+<BANKACCTFROM><BANKID>1234<ACCTID>5678<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240101
+<DTEND>20240201
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240130120000.000[-5:EST]
+<TRNAMT>-45.50
+<FITID>A-1
+<NAME>ICA Supermarket
+<MEMO>Card purchase
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240201
+<TRNAMT>100,00
+<FITID>A-2
+<NAME>Salary &amp; bonus
+<CURRENCY><CURRATE>11.2<CURSYM>EUR</CURRENCY>
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`,
+			wantDoc: &document.Document{
+				Transactions: []document.Transaction{
+					{
+						Description: "ICA Supermarket Card purchase",
+						Date:        date_20240130,
+						Amount:      document.MustParseMoney("-45.50"),
+						Currency:    document.SEK,
+						Reference:   "A-1",
+					},
+					{
+						Description: "Salary & bonus",
+						Date:        date_20240201,
+						Amount:      document.MustParseMoney("100.00"),
+						Currency:    document.EUR,
+						Reference:   "A-2",
+					},
+				},
+			},
+		},
+		{
+			name: "xml v2",
+			content: `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <CREDITCARDMSGSRSV1>
+    <CCSTMTTRNRS>
+      <CCSTMTRS>
+        <CURDEF>USD</CURDEF>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20240130</DTPOSTED>
+            <TRNAMT>-10.00</TRNAMT>
+            <FITID>X1</FITID>
+            <NAME>Booksirens</NAME>
+            <MEMO></MEMO>
+          </STMTTRN>
+        </BANKTRANLIST>
+      </CCSTMTRS>
+    </CCSTMTTRNRS>
+  </CREDITCARDMSGSRSV1>
+</OFX>
+`,
+			wantDoc: &document.Document{
+				Transactions: []document.Transaction{
+					{
+						Description: "Booksirens",
+						Date:        date_20240130,
+						Amount:      document.MustParseMoney("-10.00"),
+						Currency:    document.USD,
+						Reference:   "X1",
+					},
+				},
+			},
+		},
+		{
+			name:    "not ofx",
+			content: "Date,Amount\n2024-01-30,10\n",
+			wantErr: importer.ErrInvalidOfx,
+		},
+		{
+			name: "bad amount",
+			content: `<OFX><STMTRS><CURDEF>SEK<BANKTRANLIST>
+<STMTTRN><DTPOSTED>20240130<TRNAMT>abc<FITID>1</STMTTRN>
+</BANKTRANLIST></STMTRS></OFX>`,
+			wantErr: importer.ErrInvalidOfx,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "statement.ofx")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			gotDoc, gotErr := importer.NewOfx().Import(path, nil)
+			require.ErrorIs(t, gotErr, tc.wantErr)
+			if gotErr == nil {
+				require.EqualValues(t, tc.wantDoc, gotDoc)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package importertest
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lazeratops/optimusdime/mocks"
+	"github.com/lazeratops/optimusdime/src/importer"
+	"github.com/lazeratops/optimusdime/src/parser"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func writeXlsx(t *testing.T, files map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "statement.xlsx")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return path
+}
+
+var xlsxFiles = map[string]string{
+	"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <sheets>
+    <sheet name="Summary" sheetId="1" r:id="rId1"/>
+    <sheet name="Transactions" sheetId="2" r:id="rId2"/>
+  </sheets>
+</workbook>`,
+	"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+  <Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet2.xml"/>
+</Relationships>`,
+	"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <si><t>Datum</t></si>
+  <si><t>Belopp</t></si>
+  <si><r><t>ICA </t></r><r><t>Maxi</t></r></si>
+  <si><t>Account summary</t></si>
+</sst>`,
+	"xl/styles.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <numFmts count="2">
+    <numFmt numFmtId="164" formatCode="yyyy\-mm\-dd"/>
+    <numFmt numFmtId="165" formatCode="#,##0.00\ &quot;kr&quot;"/>
+  </numFmts>
+  <cellXfs count="4">
+    <xf numFmtId="0"/>
+    <xf numFmtId="164"/>
+    <xf numFmtId="165"/>
+    <xf numFmtId="14"/>
+  </cellXfs>
+</styleSheet>`,
+	"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row r="1"><c r="A1" t="s"><v>3</v></c></row>
+    <row r="2"><c r="B2"><v>42</v></c></row>
+  </sheetData>
+</worksheet>`,
+	"xl/worksheets/sheet2.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>
+    <row r="2"><c r="A2" s="1"><v>45292</v></c><c r="B2" t="s"><v>2</v></c><c r="C2" s="2"><v>-1234.5</v></c></row>
+    <row r="3"></row>
+    <row r="4"><c r="A4" s="3"><v>45293.5</v></c><c r="B4" t="inlineStr"><is><t>Swish</t></is></c><c r="C4"><v>99</v></c></row>
+  </sheetData>
+</worksheet>`,
+}
+
+func TestReadXlsx(t *testing.T) {
+	t.Parallel()
+	path := writeXlsx(t, xlsxFiles)
+
+	cases := []struct {
+		name        string
+		sheet       string
+		wantRecords [][]string
+		wantErr     error
+	}{
+		{
+			name:  "auto-detect transaction sheet",
+			sheet: "",
+			wantRecords: [][]string{
+				{"Datum", "", "Belopp"},
+				{"2024-01-01", "ICA Maxi", "-1234.5"},
+				{"2024-01-02T12:00:00Z", "Swish", "99"},
+			},
+		},
+		{
+			name:  "named sheet",
+			sheet: "Summary",
+			wantRecords: [][]string{
+				{"Account summary", ""},
+				{"", "42"},
+			},
+		},
+		{
+			name:    "unknown sheet",
+			sheet:   "Nope",
+			wantErr: importer.ErrInvalidXlsx,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			gotRecords, gotErr := importer.ReadXlsx(path, tc.sheet)
+			require.ErrorIs(t, gotErr, tc.wantErr)
+			if gotErr == nil {
+				require.EqualValues(t, tc.wantRecords, gotRecords)
+			}
+		})
+	}
+}
+
+func TestReadXlsxCellsWithoutReference(t *testing.T) {
+	t.Parallel()
+	files := make(map[string]string, len(xlsxFiles))
+	for name, content := range xlsxFiles {
+		files[name] = content
+	}
+	files["xl/worksheets/sheet1.xml"] = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row><c t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c><c><v>7</v></c></row>
+    <row><c r="B2" t="s"><v>2</v></c><c><v>42</v></c></row>
+  </sheetData>
+</worksheet>`
+
+	got, err := importer.ReadXlsx(writeXlsx(t, files), "Summary")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"Datum", "", "Belopp", "7"},
+		{"", "ICA Maxi", "42", ""},
+	}, got)
+}
+
+func TestReadXlsxEmpty(t *testing.T) {
+	t.Parallel()
+	files := make(map[string]string, len(xlsxFiles))
+	for name, content := range xlsxFiles {
+		files[name] = content
+	}
+	empty := `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData><row r="1"></row></sheetData>
+</worksheet>`
+	files["xl/worksheets/sheet1.xml"] = empty
+	files["xl/worksheets/sheet2.xml"] = empty
+
+	_, err := importer.ReadXlsx(writeXlsx(t, files), "")
+	require.ErrorIs(t, err, importer.ErrEmptyXlsx)
+}
+
+func TestXlsxImportRowNumbers(t *testing.T) {
+	t.Parallel()
+	files := make(map[string]string, len(xlsxFiles))
+	for name, content := range xlsxFiles {
+		files[name] = content
+	}
+	// Row 3 is empty and rows 4 and 5 are missing from the file.
+	files["xl/worksheets/sheet2.xml"] = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="inlineStr"><is><t>Text</t></is></c><c r="C1" t="s"><v>1</v></c></row>
+    <row r="2"><c r="A2" s="1"><v>45292</v></c><c r="B2" t="s"><v>2</v></c><c r="C2"><v>-1234.5</v></c></row>
+    <row r="3"></row>
+    <row r="6"><c r="A6" s="1"><v>45293</v></c><c r="B6" t="inlineStr"><is><t>Swish</t></is></c><c r="C6" t="inlineStr"><is><t>abc</t></is></c></row>
+    <row r="7"><c r="A7" s="1"><v>45294</v></c><c r="B7" t="inlineStr"><is><t>Salary</t></is></c><c r="C7"><v>2000</v></c></row>
+  </sheetData>
+</worksheet>`
+	path := writeXlsx(t, files)
+
+	records, rows, err := importer.ReadXlsxRows(path, "Transactions")
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	require.Equal(t, []int{1, 2, 6, 7}, rows)
+
+	mockLlm := mocks.NewLlm(t)
+	mockLlm.On("FindElements", mock.Anything, mock.Anything, mock.Anything).Return(map[string]int{
+		"date":        0,
+		"description": 1,
+		"amount":      2,
+	}, nil)
+	p := parser.NewParser(mockLlm, &parser.Config{DefaultCurrency: "SEK"})
+	doc, report, err := importer.NewXlsx(p).Import(context.Background(), path, &importer.XlsxConfig{Sheet: "Transactions"})
+	require.NoError(t, err)
+	require.Len(t, doc.Transactions, 2)
+	rejected := report.Rejected()
+	require.Len(t, rejected, 1)
+	require.Equal(t, 6, rejected[0].Row)
+}
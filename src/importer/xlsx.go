@@ -0,0 +1,450 @@
+package importer
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/lazeratops/optimusdime/src/parser"
+	"github.com/lazeratops/optimusdime/src/util"
+)
+
+var (
+	ErrInvalidXlsx = errors.New("invalid XLSX workbook")
+	// ErrEmptyXlsx means every sheet of the workbook is empty.
+	ErrEmptyXlsx = errors.New("no data in any XLSX sheet")
+)
+
+type Xlsx struct {
+	parser *parser.Parser
+}
+
+type XlsxConfig struct {
+	// Sheet is the name of the sheet to import. When empty, the sheet that
+	// looks most like a transaction grid is used.
+	Sheet string
+}
+
+func NewXlsx(parser *parser.Parser) *Xlsx {
+	return &Xlsx{
+		parser: parser,
+	}
+}
+
+func (x *Xlsx) Import(ctx context.Context, filePath string, config *XlsxConfig) (*document.Document, *parser.Report, error) {
+	var sheet string
+	if config != nil {
+		sheet = config.Sheet
+	}
+	records, rows, err := ReadXlsxRows(filePath, sheet)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("xlsx sheet is empty")
+	}
+	return x.parser.ParseRows(ctx, records, rows)
+}
+
+// ReadXlsx returns the cells of a sheet as records. Cells that Excel stores
+// as dates are rendered as ISO 8601 and numeric cells as plain decimals, so
+// no locale formatting has to be undone later.
+func ReadXlsx(filePath string, sheet string) ([][]string, error) {
+	records, _, err := ReadXlsxRows(filePath, sheet)
+	return records, err
+}
+
+// ReadXlsxRows is ReadXlsx that also returns the sheet row number of each
+// record, as empty rows are left out.
+func ReadXlsxRows(filePath string, sheet string) ([][]string, []int, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open XLSX: %w", err)
+	}
+	defer zr.Close()
+
+	wb, err := loadWorkbook(&zr.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if sheet != "" {
+		target, ok := wb.sheets[sheet]
+		if !ok {
+			return nil, nil, fmt.Errorf("sheet %q not found: %w", sheet, ErrInvalidXlsx)
+		}
+		return wb.readSheet(target)
+	}
+
+	var best [][]string
+	var bestRows []int
+	bestScore := -1
+	for _, name := range wb.sheetOrder {
+		records, rows, err := wb.readSheet(wb.sheets[name])
+		if err != nil {
+			return nil, nil, err
+		}
+		// On a tie, a sheet with rows beats an empty one.
+		score := scoreTransactionGrid(records)
+		if score > bestScore || (score == bestScore && best == nil) {
+			best, bestRows, bestScore = records, rows, score
+		}
+	}
+	if len(wb.sheetOrder) == 0 {
+		return nil, nil, fmt.Errorf("workbook has no sheets: %w", ErrInvalidXlsx)
+	}
+	if best == nil {
+		return nil, nil, ErrEmptyXlsx
+	}
+	return best, bestRows, nil
+}
+
+// scoreTransactionGrid counts rows that hold both a date and a number.
+func scoreTransactionGrid(records [][]string) int {
+	score := 0
+	for _, record := range records {
+		var hasDate, hasNumber bool
+		for _, cell := range record {
+			if cell == "" {
+				continue
+			}
+			if _, err := util.ParseDate(cell); err == nil {
+				hasDate = true
+			} else if _, err := strconv.ParseFloat(cell, 64); err == nil {
+				hasNumber = true
+			}
+		}
+		if hasDate && hasNumber {
+			score++
+		}
+	}
+	return score
+}
+
+type workbook struct {
+	files         map[string]*zip.File
+	sheets        map[string]string
+	sheetOrder    []string
+	sharedStrings []string
+	dateStyles    map[int]bool
+	date1904      bool
+}
+
+type xlsxWorkbook struct {
+	Properties struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		Id   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		Id     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (r xlsxRichText) String() string {
+	if len(r.Runs) == 0 {
+		return r.Text
+	}
+	var b strings.Builder
+	for _, run := range r.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		Id   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtId int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		// Number is the 1-based row number, absent in some generated files.
+		Number int `xml:"r,attr"`
+		Cells  []struct {
+			Ref       string       `xml:"r,attr"`
+			Style     int          `xml:"s,attr"`
+			Type      string       `xml:"t,attr"`
+			Value     string       `xml:"v"`
+			InlineStr xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func loadWorkbook(zr *zip.Reader) (*workbook, error) {
+	wb := &workbook{
+		files:      make(map[string]*zip.File),
+		sheets:     make(map[string]string),
+		dateStyles: make(map[int]bool),
+	}
+	for _, f := range zr.File {
+		wb.files[f.Name] = f
+	}
+
+	var xwb xlsxWorkbook
+	if err := wb.decode("xl/workbook.xml", &xwb); err != nil {
+		return nil, err
+	}
+	wb.date1904 = xwb.Properties.Date1904
+
+	var rels xlsxRelationships
+	if err := wb.decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string)
+	for _, r := range rels.Relationships {
+		target := r.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join("xl", target)
+		}
+		targets[r.Id] = target
+	}
+	for _, s := range xwb.Sheets {
+		target, ok := targets[s.Id]
+		if !ok {
+			return nil, fmt.Errorf("sheet %q has no relationship: %w", s.Name, ErrInvalidXlsx)
+		}
+		wb.sheets[s.Name] = target
+		wb.sheetOrder = append(wb.sheetOrder, s.Name)
+	}
+
+	if _, ok := wb.files["xl/sharedStrings.xml"]; ok {
+		var sst xlsxSharedStrings
+		if err := wb.decode("xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+		for _, si := range sst.Items {
+			wb.sharedStrings = append(wb.sharedStrings, si.String())
+		}
+	}
+
+	if _, ok := wb.files["xl/styles.xml"]; ok {
+		var styles xlsxStyles
+		if err := wb.decode("xl/styles.xml", &styles); err != nil {
+			return nil, err
+		}
+		customDates := make(map[int]bool)
+		for _, f := range styles.NumFmts {
+			customDates[f.Id] = isDateFormatCode(f.Code)
+		}
+		for i, xf := range styles.CellXfs {
+			if isBuiltinDateFormat(xf.NumFmtId) || customDates[xf.NumFmtId] {
+				wb.dateStyles[i] = true
+			}
+		}
+	}
+
+	return wb, nil
+}
+
+func (wb *workbook) decode(name string, v interface{}) error {
+	f, ok := wb.files[name]
+	if !ok {
+		return fmt.Errorf("missing %s: %w", name, ErrInvalidXlsx)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %v: %w", name, err, ErrInvalidXlsx)
+	}
+	return nil
+}
+
+// readSheet returns the non-empty rows of a sheet and their row numbers.
+func (wb *workbook) readSheet(target string) ([][]string, []int, error) {
+	var sheet xlsxSheet
+	if err := wb.decode(target, &sheet); err != nil {
+		return nil, nil, err
+	}
+
+	var records [][]string
+	var numbers []int
+	width := 0
+	number := 0
+	for _, row := range sheet.Rows {
+		// A row without a number follows the previous one.
+		number++
+		if row.Number > 0 {
+			number = row.Number
+		}
+		var record []string
+		empty := true
+		// A cell without a reference follows the previous one.
+		col := -1
+		for _, c := range row.Cells {
+			col++
+			if c.Ref != "" {
+				idx, err := columnIndex(c.Ref)
+				if err != nil {
+					return nil, nil, err
+				}
+				col = idx
+			}
+			for len(record) <= col {
+				record = append(record, "")
+			}
+			value, err := wb.cellValue(c.Type, c.Style, c.Value, c.InlineStr)
+			if err != nil {
+				return nil, nil, fmt.Errorf("cell %s: %w", c.Ref, err)
+			}
+			record[col] = value
+			if value != "" {
+				empty = false
+			}
+		}
+		if empty {
+			continue
+		}
+		if len(record) > width {
+			width = len(record)
+		}
+		records = append(records, record)
+		numbers = append(numbers, number)
+	}
+
+	// Parser expects a rectangular grid.
+	for i := range records {
+		for len(records[i]) < width {
+			records[i] = append(records[i], "")
+		}
+	}
+	return records, numbers, nil
+}
+
+func (wb *workbook) cellValue(cellType string, style int, value string, inline xlsxRichText) (string, error) {
+	switch cellType {
+	case "s":
+		idx, err := strconv.Atoi(value)
+		if err != nil || idx < 0 || idx >= len(wb.sharedStrings) {
+			return "", fmt.Errorf("bad shared string index %q: %w", value, ErrInvalidXlsx)
+		}
+		return strings.TrimSpace(wb.sharedStrings[idx]), nil
+	case "inlineStr":
+		return strings.TrimSpace(inline.String()), nil
+	case "str", "e":
+		return strings.TrimSpace(value), nil
+	case "b":
+		if value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case "d":
+		// ISO 8601 date stored as text.
+		return value, nil
+	}
+
+	if value == "" {
+		return "", nil
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "", fmt.Errorf("bad numeric value %q: %w", value, ErrInvalidXlsx)
+	}
+	if wb.dateStyles[style] {
+		return formatExcelDate(n, wb.date1904), nil
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64), nil
+}
+
+func formatExcelDate(serial float64, date1904 bool) string {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	t := epoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+	if seconds == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
+}
+
+// columnIndex converts a cell reference such as "AB12" to a 0-based column.
+func columnIndex(ref string) (int, error) {
+	col := 0
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("bad cell reference %q: %w", ref, ErrInvalidXlsx)
+	}
+	return col - 1, nil
+}
+
+func isBuiltinDateFormat(id int) bool {
+	return (id >= 14 && id <= 22) || (id >= 27 && id <= 36) || (id >= 45 && id <= 47) || (id >= 50 && id <= 58)
+}
+
+func isDateFormatCode(code string) bool {
+	var b strings.Builder
+	inQuotes, inBrackets, escaped := false, false, false
+	for _, r := range code {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case r == '[':
+			inBrackets = true
+		case r == ']':
+			inBrackets = false
+		case inBrackets:
+		default:
+			b.WriteRune(r)
+		}
+	}
+	stripped := strings.ToLower(b.String())
+	if stripped == "general" {
+		return false
+	}
+	return strings.ContainsAny(stripped, "dmyhs")
+}
@@ -0,0 +1,396 @@
+package llm
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/lazeratops/optimusdime/src/util"
+)
+
+const DefaultMinConfidence = 0.6
+
+var ErrLowConfidence = errors.New("column detection confidence too low")
+
+// Heuristic finds columns from header keywords and the shape of the values
+// below them. When it is not confident enough it defers to the fallback
+// Llm, if one is set.
+type Heuristic struct {
+	fallback      Llm
+	minConfidence float64
+}
+
+func NewHeuristic(fallback Llm, minConfidence float64) *Heuristic {
+	if minConfidence <= 0 {
+		minConfidence = DefaultMinConfidence
+	}
+	return &Heuristic{
+		fallback:      fallback,
+		minConfidence: minConfidence,
+	}
+}
+
+// headerKeywords are matched against whole words of a header cell, in
+// English, Swedish, German, French, Spanish and Dutch.
+var headerKeywords = map[Name][]string{
+	"date": {
+		"date", "transaction date", "booking date", "posting date", "posted", "value date",
+		"datum", "bokföringsdag", "bokföringsdatum", "transaktionsdag", "transaktionsdatum", "valutadag", "reskontradatum",
+		"buchungstag", "buchungsdatum", "valuta datum", "wertstellung",
+		"fecha", "data", "boekdatum",
+	},
+	"amount": {
+		"amount", "transaction amount", "sum", "value",
+		"belopp", "summa",
+		"betrag", "umsatz",
+		"montant", "importe", "bedrag",
+	},
+	"currency": {
+		"currency", "ccy", "currency code",
+		"valuta", "währung", "waehrung", "devise", "moneda", "munt",
+	},
+	"description": {
+		"description", "details", "narrative", "text", "memo", "payee", "merchant", "reference",
+		"beskrivning", "meddelande", "specifikation", "rubrik", "transaktion",
+		"verwendungszweck", "buchungstext", "beschreibung",
+		"libellé", "libelle", "concepto", "descripción", "omschrijving", "mededelingen",
+	},
+	"debit": {
+		"debit", "debits", "withdrawal", "withdrawals", "paid out", "money out", "out",
+		"uttag", "utbetalning", "belastning",
+		"soll", "ausgang", "lastschrift",
+		"débit", "cargo", "cargos", "af",
+	},
+	"credit": {
+		"credit", "credits", "deposit", "deposits", "paid in", "money in", "in",
+		"insättning", "inbetalning",
+		"haben", "eingang", "gutschrift",
+		"crédit", "abono", "abonos", "bij",
+	},
+	"direction": {
+		"direction", "debit credit", "credit debit", "dr cr", "cr dr", "d c", "c d", "in out",
+		"debit credit indicator", "cdtdbtind",
+		"soll haben", "s h", "af bij",
+	},
+}
+
+// negativeKeywords rule a column out for an element even when its values
+// have the right shape, e.g. a running balance is numeric but not an amount.
+var negativeKeywords = map[Name][]string{
+	"amount":      {"balance", "saldo", "kontostand", "solde", "fee", "fees", "rate", "kurs", "id"},
+	"date":        {"id"},
+	"description": {"id"},
+}
+
+var currencyCodeRe = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type column struct {
+	header string
+	values []string
+}
+
+func (h *Heuristic) FindElements(ctx context.Context, elements DesiredElements, content string) (map[string]int, error) {
+	indices, confidence, err := h.detect(elements, content)
+	if err == nil && confidence >= h.minConfidence {
+		return indices, nil
+	}
+	if h.fallback != nil {
+		return h.fallback.FindElements(ctx, elements, content)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("confidence %.2f is below %.2f: %w", confidence, h.minConfidence, ErrLowConfidence)
+}
+
+func (h *Heuristic) detect(elements DesiredElements, content string) (map[string]int, float64, error) {
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read content: %w", err)
+	}
+
+	columns, hasHeader := toColumns(records)
+	if len(columns) == 0 {
+		return nil, 0, fmt.Errorf("no columns found: %w", ErrLowConfidence)
+	}
+
+	type candidate struct {
+		element     Name
+		column      int
+		score       float64
+		headerMatch bool
+	}
+	var candidates []candidate
+	for element, e := range elements {
+		for i, col := range columns {
+			score, headerMatch := scoreColumn(element, col, hasHeader)
+			// Optional columns are only trusted when the header names them,
+			// otherwise e.g. a balance column would pass for a debit column.
+			// Nothing but a currency column holds only ISO codes, though.
+			trusted := headerMatch || (element == "currency" && isCurrencyColumn(col.values))
+			if e.Optional && (!trusted || score < h.minConfidence) {
+				continue
+			}
+			candidates = append(candidates, candidate{
+				element:     element,
+				column:      i,
+				score:       score,
+				headerMatch: headerMatch,
+			})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		if candidates[i].element != candidates[j].element {
+			return candidates[i].element < candidates[j].element
+		}
+		return candidates[i].column < candidates[j].column
+	})
+
+	// Greedily give each element its best column that is still free.
+	indices := make(map[string]int, len(elements))
+	scores := make(map[string]float64, len(elements))
+	headerMatches := make(map[string]bool, len(elements))
+	taken := make(map[int]bool)
+	for _, c := range candidates {
+		if _, done := indices[c.element]; done || taken[c.column] || c.score <= 0 {
+			continue
+		}
+		indices[c.element] = c.column
+		scores[c.element] = c.score
+		headerMatches[c.element] = c.headerMatch
+		taken[c.column] = true
+	}
+
+	for element, e := range elements {
+		if _, ok := indices[element]; !ok {
+			indices[element] = NotFound
+			continue
+		}
+		// Drop an element that was only matched by shape when one of its
+		// named alternatives was found.
+		if len(e.Alternatives) > 0 && !headerMatches[element] && !e.Required(indices) {
+			indices[element] = NotFound
+		}
+	}
+
+	confidence := 1.0
+	for element, e := range elements {
+		if indices[element] == NotFound {
+			if e.Required(indices) {
+				return nil, 0, fmt.Errorf("no column found for %q: %w", element, ErrLowConfidence)
+			}
+			continue
+		}
+		if scores[element] < confidence {
+			confidence = scores[element]
+		}
+	}
+	return indices, confidence, nil
+}
+
+// toColumns transposes records into columns. The first row is treated as a
+// header when none of its cells look like data.
+func toColumns(records [][]string) ([]column, bool) {
+	width := 0
+	for _, r := range records {
+		if len(r) > width {
+			width = len(r)
+		}
+	}
+	if len(records) == 0 || width == 0 {
+		return nil, false
+	}
+
+	hasHeader := isHeaderRow(records[0])
+	data := records
+	columns := make([]column, width)
+	if hasHeader {
+		for i, cell := range records[0] {
+			columns[i].header = strings.TrimSpace(cell)
+		}
+		data = records[1:]
+	}
+	for _, r := range data {
+		for i := 0; i < width; i++ {
+			var v string
+			if i < len(r) {
+				v = strings.TrimSpace(r[i])
+			}
+			columns[i].values = append(columns[i].values, v)
+		}
+	}
+	return columns, hasHeader
+}
+
+func isHeaderRow(record []string) bool {
+	nonEmpty := 0
+	for _, cell := range record {
+		cell = strings.TrimSpace(cell)
+		if cell == "" {
+			continue
+		}
+		nonEmpty++
+		if looksLikeDate(cell) || looksLikeAmount(cell) {
+			return false
+		}
+	}
+	return nonEmpty > 0
+}
+
+func scoreColumn(element Name, col column, hasHeader bool) (float64, bool) {
+	header := normalizeHeader(col.header)
+	for _, kw := range negativeKeywords[element] {
+		if containsWord(header, kw) {
+			return 0, false
+		}
+	}
+
+	var headerScore float64
+	for _, kw := range headerKeywords[element] {
+		switch {
+		case header == kw:
+			headerScore = 1
+		case containsWord(header, kw) && headerScore < 0.8:
+			headerScore = 0.8
+		}
+	}
+
+	shape, known := shapeScore(element, col.values)
+	if !known {
+		return 0, false
+	}
+	if !hasHeader {
+		return 0.9 * shape, false
+	}
+	if shape == 0 {
+		return 0, false
+	}
+	return 0.4*headerScore + 0.6*shape, headerScore > 0
+}
+
+// shapeScore returns the fraction of non-empty values that fit the element.
+func shapeScore(element Name, values []string) (float64, bool) {
+	var fits func(string) bool
+	switch element {
+	case "date":
+		fits = looksLikeDate
+	case "amount", "debit", "credit":
+		fits = looksLikeAmount
+	case "direction":
+		fits = func(v string) bool {
+			_, ok := util.ParseDirection(v)
+			return ok
+		}
+	case "currency":
+		fits = func(v string) bool {
+			return currencyCodeRe.MatchString(strings.ToUpper(v))
+		}
+	case "description":
+		fits = looksLikeText
+	default:
+		return 0, false
+	}
+
+	total, matched := 0, 0
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		total++
+		if fits(v) {
+			matched++
+		}
+	}
+	if total == 0 {
+		return 0, true
+	}
+	return float64(matched) / float64(total), true
+}
+
+// isCurrencyColumn reports whether the column has values and all of them
+// are ISO 4217 codes.
+func isCurrencyColumn(values []string) bool {
+	n := 0
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		if !currencyCodeRe.MatchString(v) {
+			return false
+		}
+		if _, err := document.Currency(v).Normalize(); err != nil {
+			return false
+		}
+		n++
+	}
+	return n > 0
+}
+
+func looksLikeDate(v string) bool {
+	_, err := util.ParseDate(v)
+	return err == nil
+}
+
+func looksLikeAmount(v string) bool {
+	digits := 0
+	for _, r := range v {
+		switch {
+		case unicode.IsDigit(r):
+			digits++
+		case strings.ContainsRune(" .,'-+()− ", r):
+		case unicode.IsLetter(r) || unicode.Is(unicode.Sc, r):
+			// Currency codes and symbols such as "kr", "SEK" or "€".
+		default:
+			return false
+		}
+	}
+	if digits == 0 {
+		return false
+	}
+	// Dates such as 20240130 are made of digits only.
+	return !looksLikeDate(v) && letterCount(v) <= 3
+}
+
+func looksLikeText(v string) bool {
+	return letterCount(v) >= 3 && !looksLikeDate(v)
+}
+
+func letterCount(v string) int {
+	n := 0
+	for _, r := range v {
+		if unicode.IsLetter(r) {
+			n++
+		}
+	}
+	return n
+}
+
+func normalizeHeader(h string) string {
+	h = strings.ToLower(h)
+	h = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, h)
+	return strings.Join(strings.Fields(h), " ")
+}
+
+func containsWord(header, keyword string) bool {
+	if header == "" {
+		return false
+	}
+	return strings.Contains(" "+header+" ", " "+keyword+" ")
+}
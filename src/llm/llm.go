@@ -1,15 +1,48 @@
 package llm
 
+import (
+	"context"
+	"net/http"
+)
+
 type Llm interface {
-	FindElements(elements DesiredElements, content string) (map[string]int, error)
+	FindElements(ctx context.Context, elements DesiredElements, content string) (map[string]int, error)
 }
 
 type Config struct {
 	ApiKey string
 	ApiUrl string
+	// HttpClient is used for API requests when set.
+	HttpClient *http.Client
 }
 
+// NotFound is returned as the index of an element that is not present.
+const NotFound = -1
+
 type Name = string
 type Description = string
 
-type DesiredElements map[Name]Description
+type Element struct {
+	Description Description
+	// Optional elements may be absent, in which case their index is NotFound.
+	Optional bool
+	// Alternatives lets a required element be absent when any of the named
+	// elements is found instead.
+	Alternatives []Name
+}
+
+// Required reports whether the element must be found given the indices of
+// the other elements.
+func (e Element) Required(indices map[string]int) bool {
+	if e.Optional {
+		return false
+	}
+	for _, alt := range e.Alternatives {
+		if i, ok := indices[alt]; ok && i != NotFound {
+			return false
+		}
+	}
+	return true
+}
+
+type DesiredElements map[Name]Element
@@ -12,7 +12,7 @@ import (
 )
 
 const (
-	systemMsg = "You are a bot parsing bank statements imported as a string in CSV format to extract column IDs for each specified column type. You will return the IDs (in 0-index array format) of each desired column. CSV contents:"
+	systemMsg = "You are a bot parsing bank statements imported as a string in CSV format to extract column IDs for each specified column type. You will return the IDs (in 0-index array format) of each desired column. Return -1 for a column that does not exist. CSV contents:"
 )
 
 // var DocumentSchema = generateSchema[document.Document]()
@@ -26,7 +26,16 @@ func NewOpenAi(config Config) (*OpenAi, error) {
 		return nil, errors.New("failed to instantiate OpenAi: API key not provided")
 	}
 
-	c := openai.NewClient(option.WithAPIKey(config.ApiKey))
+	opts := []option.RequestOption{
+		option.WithAPIKey(config.ApiKey),
+	}
+	if config.ApiUrl != "" {
+		opts = append(opts, option.WithBaseURL(config.ApiUrl))
+	}
+	if config.HttpClient != nil {
+		opts = append(opts, option.WithHTTPClient(config.HttpClient))
+	}
+	c := openai.NewClient(opts...)
 	return &OpenAi{
 		client: c,
 	}, nil
@@ -36,7 +45,14 @@ func createColumnIndexSchema(elements DesiredElements) map[string]interface{} {
 	properties := make(map[string]interface{})
 	required := make([]string, 0, len(elements))
 
-	for name, desc := range elements {
+	for name, element := range elements {
+		desc := element.Description
+		switch {
+		case element.Optional:
+			desc += " (optional, -1 if there is no such column)"
+		case len(element.Alternatives) > 0:
+			desc += fmt.Sprintf(" (-1 if the statement uses %s columns instead)", strings.Join(element.Alternatives, "/"))
+		}
 		properties[name] = map[string]interface{}{
 			"type":        "number",
 			"description": desc,
@@ -52,7 +68,7 @@ func createColumnIndexSchema(elements DesiredElements) map[string]interface{} {
 	}
 }
 
-func (oai *OpenAi) FindElements(elements DesiredElements, content string) (map[string]int, error) {
+func (oai *OpenAi) FindElements(ctx context.Context, elements DesiredElements, content string) (map[string]int, error) {
 
 	var toExtract []string
 
@@ -81,18 +97,16 @@ func (oai *OpenAi) FindElements(elements DesiredElements, content string) (map[s
 			JSONSchema: openai.F(schemaParam),
 		},
 	)
-	chat, err := oai.client.Chat.Completions.New(context.TODO(), openai.ChatCompletionNewParams{
+	chat, err := oai.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
 		Messages:       openai.F(msgs),
 		ResponseFormat: responseFormat,
 		Model:          openai.F(openai.ChatModelGPT4o2024_11_20),
 	})
 	if err != nil {
-		var apierr *openai.Error
-		if errors.As(err, &apierr) {
-			fmt.Println(string(apierr.DumpRequest(true)))
-			fmt.Println(string(apierr.DumpResponse(true)))
-		}
-		return nil, err
+		return nil, fmt.Errorf("failed to find columns with OpenAI: %w", err)
+	}
+	if len(chat.Choices) == 0 {
+		return nil, errors.New("failed to find columns with OpenAI: no choices in response")
 	}
 
 	completionContent := chat.Choices[0].Message.Content
@@ -0,0 +1,174 @@
+package llmtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lazeratops/optimusdime/mocks"
+	"github.com/lazeratops/optimusdime/src/llm"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var allElements = llm.DesiredElements{
+	"date":        {Description: "The date of the transaction"},
+	"amount":      {Description: "The monetary amount of the transaction", Alternatives: []llm.Name{"debit", "credit"}},
+	"debit":       {Description: "The amount taken out of the account", Optional: true},
+	"credit":      {Description: "The amount paid into the account", Optional: true},
+	"direction":   {Description: "Debit/credit indicator", Optional: true},
+	"currency":    {Description: "The currency the transaction was performed in"},
+	"description": {Description: "The description of the transaction"},
+}
+
+func TestHeuristicFindElements(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name        string
+		content     string
+		fallback    func(t *testing.T) llm.Llm
+		wantIndices map[string]int
+		wantErr     error
+	}{
+		{
+			name: "english headers",
+			content: `"TransferWise ID",Date,Amount,Currency,Description,"Payment Reference","Running Balance","Total fees"
+TRANSFER-1356004938,30-12-2024,17.76,USD,"Received money from AMAZON, INC.",PAYMENT,272.63,0.00
+CARD-2106097800,30-12-2024,-10.00,USD,"Card transaction of USD issued by Booksirens PHILADELPHIA",,222.67,0.00
+`,
+			wantIndices: map[string]int{
+				"date":        1,
+				"amount":      2,
+				"currency":    3,
+				"description": 4,
+				"debit":       llm.NotFound,
+				"credit":      llm.NotFound,
+				"direction":   llm.NotFound,
+			},
+		},
+		{
+			name: "swedish headers",
+			content: `Bokföringsdag,Valutadag,Beskrivning,Belopp,Saldo,Valuta
+2024-01-30,2024-01-31,ICA Maxi,"-1 234,50","10 000,00",SEK
+2024-01-31,2024-01-31,Swish från Anna,"200,00","10 200,00",SEK
+`,
+			wantIndices: map[string]int{
+				"date":        0,
+				"description": 2,
+				"amount":      3,
+				"currency":    5,
+				"debit":       llm.NotFound,
+				"credit":      llm.NotFound,
+				"direction":   llm.NotFound,
+			},
+		},
+		{
+			name: "separate debit and credit columns",
+			content: `Date,Description,Debit,Credit,Balance,Currency
+2024-01-30,Coffee,4.50,,995.50,GBP
+2024-01-31,Salary,,2000.00,2995.50,GBP
+`,
+			wantIndices: map[string]int{
+				"date":        0,
+				"description": 1,
+				"debit":       2,
+				"credit":      3,
+				"currency":    5,
+				"amount":      llm.NotFound,
+				"direction":   llm.NotFound,
+			},
+		},
+		{
+			name: "direction column",
+			content: `Buchungstag,Verwendungszweck,Betrag,Soll/Haben,Währung
+2024-01-30,Miete,"800,00",S,EUR
+`,
+			wantIndices: map[string]int{
+				"description": 1,
+				"amount":      2,
+				"direction":   3,
+				"currency":    4,
+				"date":        0,
+				"debit":       llm.NotFound,
+				"credit":      llm.NotFound,
+			},
+		},
+		{
+			name: "low confidence falls back",
+			content: `a,b,c,d
+x,y,z,w
+`,
+			fallback: func(t *testing.T) llm.Llm {
+				m := mocks.NewLlm(t)
+				m.On("FindElements", mock.Anything, mock.Anything, mock.Anything).Return(map[string]int{
+					"date": 0, "amount": 1, "currency": 2, "description": 3,
+				}, nil)
+				return m
+			},
+			wantIndices: map[string]int{
+				"date": 0, "amount": 1, "currency": 2, "description": 3,
+			},
+		},
+		{
+			name: "fallback error",
+			content: `a,b,c,d
+x,y,z,w
+`,
+			fallback: func(t *testing.T) llm.Llm {
+				m := mocks.NewLlm(t)
+				m.On("FindElements", mock.Anything, mock.Anything, mock.Anything).Return(nil, errSome)
+				return m
+			},
+			wantErr: errSome,
+		},
+		{
+			name: "low confidence without fallback",
+			content: `a,b,c,d
+x,y,z,w
+`,
+			wantErr: llm.ErrLowConfidence,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var fallback llm.Llm
+			if tc.fallback != nil {
+				fallback = tc.fallback(t)
+			}
+			h := llm.NewHeuristic(fallback, 0)
+			gotIndices, gotErr := h.FindElements(context.Background(), allElements, tc.content)
+			require.ErrorIs(t, gotErr, tc.wantErr)
+			if gotErr == nil {
+				require.EqualValues(t, tc.wantIndices, gotIndices)
+			}
+		})
+	}
+}
+
+var errSome = errors.New("some error")
+
+func TestHeuristicOptionalCurrency(t *testing.T) {
+	t.Parallel()
+	elements := llm.DesiredElements{
+		"date":        {Description: "The date of the transaction"},
+		"amount":      {Description: "The monetary amount of the transaction"},
+		"currency":    {Description: "The currency the transaction was performed in", Optional: true},
+		"description": {Description: "The description of the transaction"},
+	}
+
+	// Without a header, a column of ISO codes is still the currency.
+	got, err := llm.NewHeuristic(nil, 0).FindElements(context.Background(), elements, `2024-01-30,ICA Maxi,-45.50,USD
+2024-01-31,Salary,2000.00,SEK
+`)
+	require.NoError(t, err)
+	require.Equal(t, 3, got["currency"])
+
+	// Three capital letters that are not all ISO codes are not.
+	got, err = llm.NewHeuristic(nil, 0).FindElements(context.Background(), elements, `2024-01-30,ICA Maxi,-45.50,ABC
+2024-01-31,Salary,2000.00,SEK
+`)
+	require.NoError(t, err)
+	require.Equal(t, llm.NotFound, got["currency"])
+}
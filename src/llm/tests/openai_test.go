@@ -0,0 +1,58 @@
+package llmtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lazeratops/optimusdime/src/llm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAiFindElements(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name    string
+		body    string
+		want    map[string]int
+		wantErr bool
+	}{
+		{
+			name: "indices",
+			body: `{"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "{\"date\": 0, \"amount\": 2}"}}]}`,
+			want: map[string]int{"date": 0, "amount": 2},
+		},
+		{
+			name:    "no choices",
+			body:    `{"choices": []}`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			oai, err := llm.NewOpenAi(llm.Config{
+				ApiKey:     "key",
+				ApiUrl:     server.URL + "/",
+				HttpClient: server.Client(),
+			})
+			require.NoError(t, err)
+
+			got, err := oai.FindElements(context.Background(), allElements, "Date,Text,Amount")
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
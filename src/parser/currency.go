@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/lazeratops/optimusdime/src/document"
+)
+
+var currencyLabels = []string{
+	"currency", "account currency", "ccy",
+	"valuta", "kontovaluta",
+	"währung", "waehrung", "kontowährung",
+	"devise", "moneda", "munt", "valuta rekening",
+}
+
+// statementCurrency picks the currency for rows that have none of their
+// own: the configured default, then the stored profile, then a code in the
+// column headers, then a "Currency: SEK" style line above the header.
+// A configured or stored currency that is not ISO 4217 is an error.
+func (p *Parser) statementCurrency(records [][]string, l *layout) (document.Currency, document.CurrencySource, error) {
+	for _, configured := range []struct {
+		currency document.Currency
+		source   document.CurrencySource
+	}{
+		{p.defaultCurrency, document.CurrencySourceDefault},
+		{l.currency, document.CurrencySourceProfile},
+	} {
+		if configured.currency == "" {
+			continue
+		}
+		c, err := configured.currency.Normalize()
+		if err != nil {
+			return "", "", fmt.Errorf("%s statement currency: %w", configured.source, err)
+		}
+		return c, configured.source, nil
+	}
+	if c, ok := currencyFromHeader(l.header, l.indices); ok {
+		return c, document.CurrencySourceHeader, nil
+	}
+	if c, ok := currencyFromMetadata(records, l.headerRow); ok {
+		return c, document.CurrencySourceMetadata, nil
+	}
+	return "", "", nil
+}
+
+// currencyFromHeader finds headers such as "Belopp (SEK)" or "Amount EUR".
+// A bare code only counts in an amount column's header, as words such as
+// "ALL" or "TOP" are codes too; other headers need brackets.
+func currencyFromHeader(header []string, indices map[string]int) (document.Currency, bool) {
+	if len(header) == 0 {
+		return "", false
+	}
+	for _, name := range []string{"amount", "debit", "credit"} {
+		if i := indices[name]; i >= 0 && i < len(header) {
+			if c, ok := currencyInLabel(header[i], true); ok {
+				return c, true
+			}
+		}
+	}
+	for _, label := range header {
+		if c, ok := currencyInLabel(label, false); ok {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// currencyInLabel reads a code or symbol in brackets, e.g. "Amount (€)",
+// and with bare set also a code on its own, e.g. "Amount EUR".
+func currencyInLabel(label string, bare bool) (document.Currency, bool) {
+	if open := strings.IndexAny(label, "(["); open >= 0 {
+		if end := strings.IndexAny(label[open:], ")]"); end > 0 {
+			if c, ok := document.CurrencyFromSymbol(strings.TrimSpace(label[open+1 : open+end])); ok {
+				return c, true
+			}
+		}
+	}
+	if !bare {
+		return "", false
+	}
+	for _, word := range strings.FieldsFunc(label, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		if len(word) == 3 && strings.ToUpper(word) == word {
+			if c, ok := document.CurrencyFromSymbol(word); ok {
+				return c, true
+			}
+		}
+	}
+	return "", false
+}
+
+// currencyFromMetadata looks for a currency label in the rows above the
+// header, with the code in the same cell ("Currency: SEK") or the next one.
+func currencyFromMetadata(records [][]string, headerRow int) (document.Currency, bool) {
+	end := headerRow
+	if end < 0 {
+		end = len(records)
+	}
+	for _, record := range records[:end] {
+		for i, c := range record {
+			label, value, hasValue := strings.Cut(c, ":")
+			if !isCurrencyLabel(label) {
+				continue
+			}
+			if !hasValue || strings.TrimSpace(value) == "" {
+				for _, next := range record[i+1:] {
+					if strings.TrimSpace(next) != "" {
+						value = next
+						break
+					}
+				}
+			}
+			value = strings.TrimSpace(value)
+			if len(value) != 3 {
+				continue
+			}
+			if currency, ok := document.CurrencyFromSymbol(value); ok {
+				return currency, true
+			}
+		}
+	}
+	return "", false
+}
+
+func isCurrencyLabel(s string) bool {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, label := range currencyLabels {
+		if s == label {
+			return true
+		}
+	}
+	return false
+}
@@ -1,71 +1,527 @@
 package parser
 
 import (
+	"context"
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"log"
-	"strconv"
+	"sort"
 	"strings"
 
 	"github.com/lazeratops/optimusdime/src/document"
 	"github.com/lazeratops/optimusdime/src/llm"
+	"github.com/lazeratops/optimusdime/src/profile"
 	"github.com/lazeratops/optimusdime/src/util"
 )
 
-var ErrLLMFail = errors.New("LLM call failed")
+var (
+	ErrLLMFail        = errors.New("LLM call failed")
+	ErrInvalidColumns = errors.New("invalid column indices")
+	ErrNoCurrency     = errors.New("no currency column and no statement currency")
+	ErrNoTransactions = errors.New("no transactions found")
+	// ErrAmbiguousDates means the dates fit several layouts that read
+	// them differently, such as day and month swapped. Config.DateLayout
+	// decides.
+	ErrAmbiguousDates = errors.New("dates fit more than one layout")
+)
+
+var desiredElements = llm.DesiredElements{
+	"date": {
+		Description: "The date of the transaction",
+	},
+	"amount": {
+		Description:  "The monetary amount of the transaction",
+		Alternatives: []llm.Name{"debit", "credit"},
+	},
+	"debit": {
+		Description: "The amount taken out of the account, when debits and credits are in separate columns",
+		Optional:    true,
+	},
+	"credit": {
+		Description: "The amount paid into the account, when debits and credits are in separate columns",
+		Optional:    true,
+	},
+	"direction": {
+		Description: "An indicator of whether the amount is a debit or a credit, such as D/C or In/Out, when amounts are unsigned",
+		Optional:    true,
+	},
+	"currency": {
+		Description: "The currency the transaction was performed in",
+		Optional:    true,
+	},
+	"description": {
+		Description: "The description of the transaction",
+	},
+}
+
+// ElementNames returns the names of the columns the parser looks for,
+// sorted. They are the keys of a profile's Indices.
+func ElementNames() []string {
+	names := make([]string, 0, len(desiredElements))
+	for name := range desiredElements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
 type Parser struct {
-	llm llm.Llm
+	llm             llm.Llm
+	profiles        *profile.Store
+	defaultCurrency document.Currency
+	dateLayout      string
+	rounding        document.RoundingMode
+}
+
+type Config struct {
+	// Profiles, when set, is consulted before the LLM and remembers the
+	// columns it finds for the next statement with the same header.
+	Profiles *profile.Store
+	// DefaultCurrency is the statement currency for rows that have no
+	// currency of their own.
+	DefaultCurrency document.Currency
+	// DateLayout is the Go layout of the date column. When empty it is
+	// inferred from the whole column.
+	DateLayout string
+	// Rounding applies to amounts written with more decimals than their
+	// currency has.
+	Rounding document.RoundingMode
 }
 
-func NewParser(llm llm.Llm) *Parser {
-	return &Parser{
+// layout describes where things are in a statement.
+type layout struct {
+	indices   map[string]int
+	header    []string
+	headerRow int
+	// currency comes from a stored profile, if any.
+	currency document.Currency
+}
+
+func NewParser(llm llm.Llm, config *Config) *Parser {
+	p := &Parser{
 		llm: llm,
 	}
+	if config != nil {
+		p.profiles = config.Profiles
+		p.defaultCurrency = config.DefaultCurrency
+		p.dateLayout = config.DateLayout
+		p.rounding = config.Rounding
+	}
+	return p
 }
 
-func (p *Parser) Parse(records [][]string) (*document.Document, error) {
-	var content strings.Builder
-	for _, record := range records {
-		content.WriteString(strings.Join(record, ",") + "\n")
-	}
+// Parse turns records into transactions. Every row that does not become a
+// transaction is accounted for in the returned report, and a row that
+// cannot be parsed is rejected rather than failing the whole statement.
+func (p *Parser) Parse(ctx context.Context, records [][]string) (*document.Document, *Report, error) {
+	return p.ParseRows(ctx, records, nil)
+}
 
-	indices, err := p.llm.FindElements(llm.DesiredElements{
-		"date":        "The date of the transaction",
-		"amount":      "The monetary amount of the transaction",
-		"currency":    "The currency the transaction was performed in",
-		"description": "The description of the transaction",
-	}, content.String())
+// ParseRows is Parse for records that are not numbered 1, 2, 3... in their
+// file, e.g. because blank lines were skipped. rows[i] is the 1-based row
+// of records[i], and is what the report shows.
+func (p *Parser) ParseRows(ctx context.Context, records [][]string, rows []int) (*document.Document, *Report, error) {
+	if rows != nil && len(rows) != len(records) {
+		return nil, nil, fmt.Errorf("got %d row numbers for %d records", len(rows), len(records))
+	}
+	l, err := p.findLayout(ctx, records)
 	if err != nil {
-		return nil, fmt.Errorf("%v: %w", err, ErrLLMFail)
+		return nil, nil, err
 	}
-	var transactions []document.Transaction
+	indices := l.indices
+	fallbackCurrency, fallbackSource, err := p.statementCurrency(records, l)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Decide the decimal convention once for the whole file, so that
+	// "1,234" means the same thing on every row.
+	amounts := make([]string, 0, len(records))
 	for _, record := range records {
-		date, err := util.ParseDate(record[indices["date"]])
+		for _, name := range []string{"amount", "debit", "credit"} {
+			if v := cell(record, indices[name]); v != "" {
+				amounts = append(amounts, v)
+			}
+		}
+	}
+	decimal := util.DetectDecimalSeparator(amounts)
+
+	report := &Report{
+		Rows:       len(records),
+		rowNumbers: rows,
+	}
+
+	// Likewise read every date with one layout, so that "03/04/2024" is not
+	// April on one row and March on the next.
+	dateLayout := p.dateLayout
+	if dateLayout == "" {
+		dates := make([]string, 0, len(records))
+		for i, record := range records {
+			if i == l.headerRow || (i < l.headerRow && !isTransactionRow(record)) {
+				continue
+			}
+			dates = append(dates, cell(record, indices["date"]))
+		}
+		inference, err := util.InferDateLayout(dates)
 		if err != nil {
-			log.Printf("\nfailed to parse date: %v; skipping", err)
+			return nil, nil, fmt.Errorf("failed to read the date column: %w", err)
+		}
+		dateLayout = inference.Layout
+		if len(inference.Ambiguous) > 0 {
+			report.AmbiguousDateLayouts = inference.Ambiguous
+			return nil, report, fmt.Errorf("%s and %s both fit, set the date format: %w", dateLayout, strings.Join(inference.Ambiguous, ", "), ErrAmbiguousDates)
+		}
+	}
+	report.DateLayout = dateLayout
+
+	// Rows after the last dated row that are not transactions are footer.
+	lastDated := -1
+	for i := len(records) - 1; i > l.headerRow; i-- {
+		if _, err := util.ParseDateLayout(cell(records[i], indices["date"]), dateLayout); err == nil {
+			lastDated = i
+			break
+		}
+	}
+	var fingerprint string
+	if l.header != nil {
+		fingerprint = profile.Fingerprint(l.header)
+	}
+
+	var transactions []document.Transaction
+	noCurrency := 0
+	for i, record := range records {
+		switch {
+		case i < l.headerRow && !isTransactionRow(record):
+			// Rows with a date and an amount are data even above a header
+			// found late, e.g. a label-only footer.
+			report.add(i, RowPreamble, "", "", record)
+			continue
+		case i == l.headerRow:
+			report.add(i, RowHeader, "", "", record)
+			continue
+		case isBlankRow(record):
+			report.add(i, RowBlank, "", "", record)
+			continue
+		case fingerprint != "" && profile.Fingerprint(record) == fingerprint:
+			// Header repeated, e.g. at a page break.
+			report.add(i, RowHeader, "", "repeated header", record)
+			continue
+		}
+
+		date, dateErr := util.ParseDateLayout(cell(record, indices["date"]), dateLayout)
+		if isSummaryRow(record, dateErr == nil) {
+			report.add(i, RowSummary, "", "", record)
+			continue
+		}
+		if dateErr != nil {
+			if i > lastDated {
+				report.add(i, RowFooter, "", "", record)
+			} else {
+				report.add(i, RowRejected, "date", dateErr.Error(), record)
+			}
 			continue
 		}
 
-		amount, err := strconv.ParseFloat(record[indices["amount"]], 64)
+		amount, symbol, err := signedAmount(record, indices, decimal)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse amount: %w", err)
+			field := "amount"
+			var fe *fieldError
+			if errors.As(err, &fe) {
+				field = fe.field
+			}
+			report.add(i, RowRejected, field, err.Error(), record)
+			continue
 		}
 
-		c := record[indices["currency"]]
-		currency := document.Currency(c)
+		var currency document.Currency
+		source := document.CurrencySourceColumn
+		if v := cell(record, indices["currency"]); v != "" {
+			c, err := document.Currency(v).Normalize()
+			if err != nil {
+				report.add(i, RowRejected, "currency", err.Error(), record)
+				continue
+			}
+			currency = c
+		} else {
+			// Fall back to a symbol or code written in the amount cell,
+			// then to the statement currency.
+			if c, ok := document.CurrencyFromSymbol(symbol); ok {
+				currency, source = c, document.CurrencySourceAmount
+			} else {
+				currency, source = fallbackCurrency, fallbackSource
+			}
+		}
+		if currency == "" {
+			report.add(i, RowRejected, "currency", ErrNoCurrency.Error(), record)
+			noCurrency++
+			continue
+		}
 
-		description := record[indices["description"]]
+		rounded, err := amount.RoundTo(currency, p.rounding)
+		if err != nil {
+			report.add(i, RowRejected, "amount", err.Error(), record)
+			continue
+		}
+		description := cell(record, indices["description"])
 
 		transactions = append(transactions, document.Transaction{
-			Date:        date,
-			Amount:      amount,
-			Currency:    currency,
-			Description: description,
+			Date:           date,
+			Amount:         rounded,
+			Currency:       currency,
+			Description:    description,
+			CurrencySource: source,
 		})
 	}
+	report.Transactions = len(transactions)
 
+	if len(transactions) == 0 && noCurrency > 0 {
+		return nil, report, fmt.Errorf("%d rows have no currency: %w", noCurrency, ErrNoCurrency)
+	}
+	if len(transactions) == 0 && report.Rows > 0 {
+		return nil, report, fmt.Errorf("none of %d rows is a transaction: %w", report.Rows, ErrNoTransactions)
+	}
 	return &document.Document{
 		Transactions: transactions,
-	}, nil
+	}, report, nil
+}
+
+// signedAmount combines the amount, debit/credit and direction columns into
+// one signed amount. Debits are negative. A sign written in a debit or
+// credit cell is kept, so a negative credit stays a reversal.
+func signedAmount(record []string, indices map[string]int, decimal rune) (document.Money, string, error) {
+	debit, credit := cell(record, indices["debit"]), cell(record, indices["credit"])
+	if debit != "" || credit != "" {
+		var total document.Money
+		var symbol string
+		for _, part := range []struct {
+			field string
+			value string
+			sign  int
+		}{{"debit", debit, -1}, {"credit", credit, 1}} {
+			if part.value == "" {
+				continue
+			}
+			amount, sym, err := parseAmount(part.value, decimal)
+			if err != nil {
+				return document.Money{}, "", err
+			}
+			if part.sign < 0 && amount.Sign() > 0 {
+				amount = amount.Neg()
+			}
+			total, err = total.Add(amount)
+			if err != nil {
+				return document.Money{}, "", &fieldError{field: part.field, err: err}
+			}
+			if symbol == "" {
+				symbol = sym
+			}
+		}
+		return total, symbol, nil
+	}
+
+	if indices["amount"] == llm.NotFound {
+		return document.Money{}, "", errors.New("no debit or credit amount")
+	}
+	amount, symbol, err := parseAmount(cell(record, indices["amount"]), decimal)
+	if err != nil {
+		return document.Money{}, "", err
+	}
+	if i := indices["direction"]; i != llm.NotFound {
+		sign, ok := util.ParseDirection(cell(record, i))
+		if !ok {
+			return document.Money{}, "", &fieldError{
+				field: "direction",
+				err:   fmt.Errorf("unknown debit/credit indicator %q", cell(record, i)),
+			}
+		}
+		amount = amount.Abs()
+		if sign < 0 {
+			amount = amount.Neg()
+		}
+	}
+	return amount, symbol, nil
+}
+
+func parseAmount(v string, decimal rune) (document.Money, string, error) {
+	raw, symbol, err := util.ParseAmount(v, decimal)
+	if err != nil {
+		return document.Money{}, "", err
+	}
+	amount, err := document.ParseMoney(raw)
+	if err != nil {
+		return document.Money{}, "", err
+	}
+	return amount, symbol, nil
+}
+
+// cell returns the trimmed value at index i, or "" when the column is
+// missing.
+func cell(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func (p *Parser) findLayout(ctx context.Context, records [][]string) (*layout, error) {
+	l := &layout{
+		headerRow: findHeaderRow(records),
+	}
+	var fingerprint string
+	if l.headerRow >= 0 {
+		l.header = records[l.headerRow]
+		fingerprint = profile.Fingerprint(l.header)
+	}
+
+	if p.profiles != nil && fingerprint != "" {
+		stored, err := p.profiles.Get(fingerprint)
+		switch {
+		case err == nil && stored.Fingerprint == fingerprint:
+			indices := normalizeIndices(stored.Indices)
+			if validIndices(indices, len(l.header)) == nil {
+				if err := p.profiles.MarkUsed(fingerprint); err != nil {
+					log.Printf("\nfailed to update profile %s: %v", fingerprint, err)
+				}
+				l.indices = indices
+				l.currency = stored.Currency
+				return l, nil
+			}
+		case err != nil && !errors.Is(err, profile.ErrNotFound):
+			log.Printf("\nfailed to read profile %s: %v", fingerprint, err)
+		}
+	}
+
+	// Re-encode as CSV so that cells containing commas stay in one column.
+	var content strings.Builder
+	w := csv.NewWriter(&content)
+	if err := w.WriteAll(records); err != nil {
+		return nil, fmt.Errorf("failed to encode records: %w", err)
+	}
+
+	indices, err := p.llm.FindElements(ctx, desiredElements, content.String())
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, ErrLLMFail)
+	}
+	indices = normalizeIndices(indices)
+	if err := validIndices(indices, recordWidth(records)); err != nil {
+		return nil, err
+	}
+	l.indices = indices
+
+	if p.profiles != nil && fingerprint != "" {
+		stored := profile.Profile{
+			Fingerprint: fingerprint,
+			Header:      l.header,
+			Indices:     indices,
+		}
+		if indices["currency"] == llm.NotFound {
+			stored.Currency = p.defaultCurrency
+		}
+		if err := p.profiles.Put(stored); err != nil {
+			log.Printf("\nfailed to save profile %s: %v", fingerprint, err)
+		}
+	}
+	return l, nil
+}
+
+// normalizeIndices marks elements missing from indices as not found, so
+// that mappings stored before an element existed keep working.
+func normalizeIndices(indices map[string]int) map[string]int {
+	normalized := make(map[string]int, len(desiredElements))
+	for name := range desiredElements {
+		i, ok := indices[name]
+		if !ok {
+			i = llm.NotFound
+		}
+		normalized[name] = i
+	}
+	return normalized
+}
+
+func validIndices(indices map[string]int, width int) error {
+	for name, element := range desiredElements {
+		i := indices[name]
+		if i == llm.NotFound {
+			if element.Required(indices) {
+				return fmt.Errorf("no column for %q: %w", name, ErrInvalidColumns)
+			}
+			continue
+		}
+		if i < 0 || i >= width {
+			return fmt.Errorf("column %d for %q is out of range: %w", i, name, ErrInvalidColumns)
+		}
+	}
+	return nil
+}
+
+func recordWidth(records [][]string) int {
+	width := 0
+	for _, r := range records {
+		if len(r) > width {
+			width = len(r)
+		}
+	}
+	return width
+}
+
+// findHeaderRow returns the index of the last row made up only of labels
+// before the first transaction-like row (one with both a date and an
+// amount), or -1 when there is none.
+func findHeaderRow(records [][]string) int {
+	header := -1
+	for i, record := range records {
+		hasDate, hasAmount, labels := classifyRow(record)
+		if hasDate && hasAmount {
+			break
+		}
+		if !hasDate && !hasAmount && labels >= 2 {
+			header = i
+		}
+	}
+	return header
+}
+
+// isTransactionRow reports whether a row has both a date and an amount.
+func isTransactionRow(record []string) bool {
+	hasDate, hasAmount, _ := classifyRow(record)
+	return hasDate && hasAmount
+}
+
+// classifyRow reports whether a row has a date and an amount cell, and how
+// many of its cells are neither.
+func classifyRow(record []string) (hasDate bool, hasAmount bool, labels int) {
+	for _, c := range record {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if _, err := util.ParseDate(c); err == nil {
+			hasDate = true
+		} else if isAmount(c) {
+			hasAmount = true
+		} else {
+			labels++
+		}
+	}
+	return hasDate, hasAmount, labels
+}
+
+// isAmount reports whether c reads as an amount with either decimal
+// separator, such as "-1 234,50" or "€12.50". Text next to the number
+// must be a currency, so that labels like "Account 1234" are not amounts.
+func isAmount(c string) bool {
+	for _, decimal := range []rune{'.', ','} {
+		_, symbol, err := util.ParseAmount(c, decimal)
+		if err != nil {
+			continue
+		}
+		if symbol == "" {
+			return true
+		}
+		if _, ok := document.CurrencyFromSymbol(symbol); ok {
+			return true
+		}
+	}
+	return false
 }
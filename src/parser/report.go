@@ -0,0 +1,172 @@
+package parser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// RowKind says why a row did not become a transaction.
+type RowKind string
+
+const (
+	RowPreamble RowKind = "preamble"
+	RowHeader   RowKind = "header"
+	RowBlank    RowKind = "blank"
+	RowSummary  RowKind = "summary"
+	RowFooter   RowKind = "footer"
+	RowRejected RowKind = "rejected"
+)
+
+// RowIssue describes one row that was not imported. Row is the 1-based
+// row in the source file.
+type RowIssue struct {
+	Row    int      `json:"row"`
+	Kind   RowKind  `json:"kind"`
+	Field  string   `json:"field,omitempty"`
+	Reason string   `json:"reason,omitempty"`
+	Cells  []string `json:"cells"`
+}
+
+// Report accounts for every row of a parsed statement.
+type Report struct {
+	Rows         int        `json:"rows"`
+	Transactions int        `json:"transactions"`
+	Issues       []RowIssue `json:"issues"`
+	// DateLayout is the Go layout every date was read with.
+	DateLayout string `json:"date_layout"`
+	// AmbiguousDateLayouts fit the dates as well as DateLayout but read
+	// some of them differently. They are only set when Parse fails with
+	// ErrAmbiguousDates.
+	AmbiguousDateLayouts []string `json:"ambiguous_date_layouts,omitempty"`
+
+	// rowNumbers maps record indices to source rows, see Parser.ParseRows.
+	rowNumbers []int
+}
+
+// Rejected returns the rows that looked like transactions but could not be
+// parsed.
+func (r *Report) Rejected() []RowIssue {
+	var rejected []RowIssue
+	for _, issue := range r.Issues {
+		if issue.Kind == RowRejected {
+			rejected = append(rejected, issue)
+		}
+	}
+	return rejected
+}
+
+// Count returns the number of rows of the given kind.
+func (r *Report) Count(kind RowKind) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *Report) add(row int, kind RowKind, field string, reason string, cells []string) {
+	number := row + 1
+	if row < len(r.rowNumbers) {
+		number = r.rowNumbers[row]
+	}
+	r.Issues = append(r.Issues, RowIssue{
+		Row:    number,
+		Kind:   kind,
+		Field:  field,
+		Reason: reason,
+		Cells:  cells,
+	})
+}
+
+// SaveToCSV writes one line per issue. The raw cells of the row follow the
+// reason, one per column.
+func (r *Report) SaveToCSV(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"Row", "Kind", "Field", "Reason", "Cells"}
+	if err := writer.Write(headers); err != nil {
+		return fmt.Errorf("failed to write headers: %w", err)
+	}
+
+	for _, issue := range r.Issues {
+		record := append([]string{
+			strconv.Itoa(issue.Row),
+			string(issue.Kind),
+			issue.Field,
+			issue.Reason,
+		}, issue.Cells...)
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// fieldError ties a row error to the column it came from.
+type fieldError struct {
+	field string
+	err   error
+}
+
+func (e *fieldError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+// summaryLabels start rows that total or balance the statement rather than
+// record a transaction.
+var summaryLabels = []string{
+	"total", "totals", "subtotal", "sum", "grand total",
+	"opening balance", "closing balance", "balance", "balance brought forward", "balance carried forward",
+	"summa", "totalt", "saldo", "ingående saldo", "utgående saldo",
+	"summe", "gesamt", "anfangssaldo", "endsaldo", "kontostand",
+	"solde", "solde initial", "solde final", "saldo inicial", "saldo final",
+	"beginsaldo", "eindsaldo",
+}
+
+// isSummaryRow reports whether a cell holds a summary label. Rows that have
+// a valid date only count when a cell is exactly a label, so that payees
+// such as "Total Wines" stay transactions.
+func isSummaryRow(record []string, dated bool) bool {
+	for _, c := range record {
+		c = strings.ToLower(strings.TrimSpace(c))
+		c = strings.TrimSpace(strings.TrimRight(c, ":"))
+		if c == "" {
+			continue
+		}
+		for _, label := range summaryLabels {
+			if c == label {
+				return true
+			}
+			if !dated && strings.HasPrefix(c, label) && strings.ContainsAny(c[len(label):len(label)+1], " :") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isBlankRow(record []string) bool {
+	for _, c := range record {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -1,15 +1,20 @@
 package parsertest
 
 import (
+	"context"
 	"encoding/csv"
 	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/lazeratops/optimusdime/mocks"
 	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/lazeratops/optimusdime/src/llm"
 	"github.com/lazeratops/optimusdime/src/parser"
+	"github.com/lazeratops/optimusdime/src/profile"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -58,54 +63,195 @@ TRANSFER-1355191945,30-12-2024,0.02,USD,"Received money from AMAZON SE5097806 wi
 			wantDoc: document.Document{
 				Transactions: []document.Transaction{
 					{
-						Amount:      17.76,
-						Currency:    document.USD,
-						Date:        date_30122024,
-						Description: "Received money from AMAZON AUSTRALIA SERVICES  INC. with reference PAYMENT",
+						Amount:         document.MustParseMoney("17.76"),
+						Currency:       document.USD,
+						CurrencySource: document.CurrencySourceColumn,
+						Date:           date_30122024,
+						Description:    "Received money from AMAZON AUSTRALIA SERVICES  INC. with reference PAYMENT",
 					},
 					{
-						Amount:      19.87,
-						Currency:    document.USD,
-						Date:        date_30122024,
-						Description: "Received money from AMAZON.COM SERVICES LLC with reference PAYMENT",
+						Amount:         document.MustParseMoney("19.87"),
+						Currency:       document.USD,
+						CurrencySource: document.CurrencySourceColumn,
+						Date:           date_30122024,
+						Description:    "Received money from AMAZON.COM SERVICES LLC with reference PAYMENT",
 					},
 					{
-						Amount:      12.33,
-						Currency:    document.USD,
-						Date:        date_30122024,
-						Description: "Received money from AMAZON MEDIA EU S.A.R.L. with reference PAYMENT",
+						Amount:         document.MustParseMoney("12.33"),
+						Currency:       document.USD,
+						CurrencySource: document.CurrencySourceColumn,
+						Date:           date_30122024,
+						Description:    "Received money from AMAZON MEDIA EU S.A.R.L. with reference PAYMENT",
 					},
 					{
-						Amount:      -10.00,
-						Currency:    document.USD,
-						Date:        date_30122024,
-						Description: "Card transaction of USD issued by Booksirens PHILADELPHIA",
+						Amount:         document.MustParseMoney("-10.00"),
+						Currency:       document.USD,
+						CurrencySource: document.CurrencySourceColumn,
+						Date:           date_30122024,
+						Description:    "Card transaction of USD issued by Booksirens PHILADELPHIA",
 					},
 					{
-						Amount:      0.02,
-						Currency:    document.USD,
-						Date:        date_30122024,
-						Description: "Received money from AMAZON SE5097806 with reference EDI PYMNTS",
+						Amount:         document.MustParseMoney("0.02"),
+						Currency:       document.USD,
+						CurrencySource: document.CurrencySourceColumn,
+						Date:           date_30122024,
+						Description:    "Received money from AMAZON SE5097806 with reference EDI PYMNTS",
 					},
 				},
 			},
 		},
+		{
+			name: "locale amounts",
+			doc: `Datum,Belopp,Valuta,Text
+30-12-2024,"1 234,56",SEK,Lön
+30-12-2024,"-kr 45,00",,ICA
+30-12-2024,"(12,00)",EUR,Refund reversal
+`,
+			llmRes: func(t *testing.T) (map[string]int, error) {
+				return map[string]int{
+					"date":        0,
+					"amount":      1,
+					"currency":    2,
+					"description": 3,
+				}, nil
+			},
+			wantDoc: document.Document{
+				Transactions: []document.Transaction{
+					{
+						Amount:         document.MustParseMoney("1234.56"),
+						Currency:       document.SEK,
+						CurrencySource: document.CurrencySourceColumn,
+						Date:           date_30122024,
+						Description:    "Lön",
+					},
+					{
+						Amount:         document.MustParseMoney("-45.00"),
+						Currency:       document.SEK,
+						CurrencySource: document.CurrencySourceAmount,
+						Date:           date_30122024,
+						Description:    "ICA",
+					},
+					{
+						Amount:         document.MustParseMoney("-12.00"),
+						Currency:       document.EUR,
+						CurrencySource: document.CurrencySourceColumn,
+						Date:           date_30122024,
+						Description:    "Refund reversal",
+					},
+				},
+			},
+		},
+		{
+			name: "debit and credit columns",
+			// A negative credit is a reversal and keeps its sign; a debit
+			// is negative whether or not the sign is written.
+			doc: `Date,Description,Debit,Credit,Currency
+30-12-2024,Coffee,4.50,,GBP
+30-12-2024,Refund,,-2.00,GBP
+30-12-2024,Tea,-3.00,,GBP
+`,
+			llmRes: func(t *testing.T) (map[string]int, error) {
+				return map[string]int{
+					"date":        0,
+					"description": 1,
+					"amount":      -1,
+					"debit":       2,
+					"credit":      3,
+					"direction":   -1,
+					"currency":    4,
+				}, nil
+			},
+			wantDoc: document.Document{
+				Transactions: []document.Transaction{
+					{
+						Amount:         document.MustParseMoney("-4.50"),
+						Currency:       "GBP",
+						CurrencySource: document.CurrencySourceColumn,
+						Date:           date_30122024,
+						Description:    "Coffee",
+					},
+					{
+						Amount:         document.MustParseMoney("-2.00"),
+						Currency:       "GBP",
+						CurrencySource: document.CurrencySourceColumn,
+						Date:           date_30122024,
+						Description:    "Refund",
+					},
+					{
+						Amount:         document.MustParseMoney("-3.00"),
+						Currency:       "GBP",
+						CurrencySource: document.CurrencySourceColumn,
+						Date:           date_30122024,
+						Description:    "Tea",
+					},
+				},
+			},
+		},
+		{
+			name: "direction column",
+			doc: `Date,Description,Amount,Type,Currency
+30-12-2024,Rent,800.00,DR,EUR
+30-12-2024,Salary,2000.00,CR,EUR
+`,
+			llmRes: func(t *testing.T) (map[string]int, error) {
+				return map[string]int{
+					"date":        0,
+					"description": 1,
+					"amount":      2,
+					"direction":   3,
+					"currency":    4,
+				}, nil
+			},
+			wantDoc: document.Document{
+				Transactions: []document.Transaction{
+					{
+						Amount:         document.MustParseMoney("-800.00"),
+						Currency:       document.EUR,
+						CurrencySource: document.CurrencySourceColumn,
+						Date:           date_30122024,
+						Description:    "Rent",
+					},
+					{
+						Amount:         document.MustParseMoney("2000.00"),
+						Currency:       document.EUR,
+						CurrencySource: document.CurrencySourceColumn,
+						Date:           date_30122024,
+						Description:    "Salary",
+					},
+				},
+			},
+		},
+		{
+			name: "missing amount column",
+			doc: `Date,Description,Currency
+30-12-2024,Rent,EUR
+`,
+			llmRes: func(t *testing.T) (map[string]int, error) {
+				return map[string]int{
+					"date":        0,
+					"description": 1,
+					"amount":      -1,
+					"currency":    2,
+				}, nil
+			},
+			wantErr: parser.ErrInvalidColumns,
+		},
 	}
 	for _, tc := range cases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 			mockLlm := mocks.NewLlm(t)
-			mockLlm.On("FindElements", mock.Anything, mock.Anything).Return(tc.llmRes(t))
+			mockLlm.On("FindElements", mock.Anything, mock.Anything, mock.Anything).Return(tc.llmRes(t))
 
-			parser := parser.NewParser(mockLlm)
+			parser := parser.NewParser(mockLlm, nil)
 
 			reader := csv.NewReader(strings.NewReader(tc.doc))
 			reader.LazyQuotes = true // Handle inconsistent quotes
 			records, err := reader.ReadAll()
 			require.NoError(t, err)
 
-			gotDoc, gotErr := parser.Parse(records)
+			gotDoc, _, gotErr := parser.Parse(context.Background(), records)
 			require.ErrorIs(t, gotErr, tc.wantErr)
 			if gotErr == nil {
 				require.EqualValues(t, &tc.wantDoc, gotDoc)
@@ -113,3 +259,384 @@ TRANSFER-1355191945,30-12-2024,0.02,USD,"Received money from AMAZON SE5097806 wi
 		})
 	}
 }
+
+func TestParseReusesProfile(t *testing.T) {
+	t.Parallel()
+	doc := `Datum,Text,Belopp,Valuta
+2024-01-30,ICA Maxi,-45.50,SEK
+`
+	reader := csv.NewReader(strings.NewReader(doc))
+	records, err := reader.ReadAll()
+	require.NoError(t, err)
+
+	store := profile.NewStore(filepath.Join(t.TempDir(), "profiles.json"))
+	mockLlm := mocks.NewLlm(t)
+	mockLlm.On("FindElements", mock.Anything, mock.Anything, mock.Anything).Return(map[string]int{
+		"date":        0,
+		"description": 1,
+		"amount":      2,
+		"currency":    3,
+	}, nil).Once()
+
+	p := parser.NewParser(mockLlm, &parser.Config{Profiles: store})
+	first, _, err := p.Parse(context.Background(), records)
+	require.NoError(t, err)
+
+	// Header matching is case-insensitive, so this must not reach the LLM.
+	records[0] = []string{"DATUM", "Text", "Belopp", " Valuta "}
+	second, _, err := p.Parse(context.Background(), records)
+	require.NoError(t, err)
+	require.EqualValues(t, first, second)
+
+	stored, err := store.Get(profile.Fingerprint(records[0]))
+	require.NoError(t, err)
+	require.Equal(t, 1, stored.UseCount)
+	require.Equal(t, 2, stored.Indices["amount"])
+}
+
+func TestParseStatementCurrency(t *testing.T) {
+	t.Parallel()
+	indices := map[string]int{
+		"date":        0,
+		"description": 1,
+		"amount":      2,
+		"currency":    -1,
+	}
+	cases := []struct {
+		name       string
+		doc        string
+		config     *parser.Config
+		wantAmount string
+		want       document.Currency
+		wantSource document.CurrencySource
+		wantErr    error
+	}{
+		{
+			name: "amount header",
+			doc: `Datum,Text,Belopp (SEK)
+2024-01-30,ICA Maxi,"-45,50"
+`,
+			wantAmount: "-45.50",
+			want:       document.SEK,
+			wantSource: document.CurrencySourceHeader,
+		},
+		{
+			name: "bare code in amount header",
+			doc: `Date,Description,Amount EUR
+2024-01-30,Rent,-800.00
+`,
+			wantAmount: "-800.00",
+			want:       document.EUR,
+			wantSource: document.CurrencySourceHeader,
+		},
+		{
+			name: "bracketed code in another header",
+			doc: `Date,Description,Amount,Balance [GBP]
+2024-01-30,Rent,-800.00,1200.00
+`,
+			wantAmount: "-800.00",
+			want:       "GBP",
+			wantSource: document.CurrencySourceHeader,
+		},
+		{
+			name: "code-like word in another header",
+			doc: `Date,ALL TRANSACTIONS,Amount
+2024-01-30,Rent,-800.00
+`,
+			wantErr: parser.ErrNoCurrency,
+		},
+		{
+			name: "metadata row",
+			doc: `Account,1234 5678
+Currency:,EUR
+Date,Description,Amount
+2024-01-30,Rent,-800.00
+`,
+			wantAmount: "-800.00",
+			want:       document.EUR,
+			wantSource: document.CurrencySourceMetadata,
+		},
+		{
+			name: "default wins",
+			doc: `Datum,Text,Belopp (SEK)
+2024-01-30,ICA Maxi,"-45,50"
+`,
+			config:     &parser.Config{DefaultCurrency: "NOK"},
+			wantAmount: "-45.50",
+			want:       "NOK",
+			wantSource: document.CurrencySourceDefault,
+		},
+		{
+			name: "amount symbol beats default",
+			doc: `Date,Description,Amount
+2024-01-30,Lunch,€12.50
+`,
+			config:     &parser.Config{DefaultCurrency: document.SEK},
+			wantAmount: "12.50",
+			want:       document.EUR,
+			wantSource: document.CurrencySourceAmount,
+		},
+		{
+			name: "no currency",
+			doc: `Date,Description,Amount
+2024-01-30,Rent,-800.00
+`,
+			wantErr: parser.ErrNoCurrency,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mockLlm := mocks.NewLlm(t)
+			mockLlm.On("FindElements", mock.Anything, mock.Anything, mock.Anything).Return(indices, nil)
+
+			reader := csv.NewReader(strings.NewReader(tc.doc))
+			reader.FieldsPerRecord = -1
+			records, err := reader.ReadAll()
+			require.NoError(t, err)
+
+			doc, _, err := parser.NewParser(mockLlm, tc.config).Parse(context.Background(), records)
+			require.ErrorIs(t, err, tc.wantErr)
+			if err != nil {
+				return
+			}
+			require.Len(t, doc.Transactions, 1)
+			require.Equal(t, tc.wantAmount, doc.Transactions[0].Amount.String())
+			require.Equal(t, tc.want, doc.Transactions[0].Currency)
+			require.Equal(t, tc.wantSource, doc.Transactions[0].CurrencySource)
+		})
+	}
+}
+
+func TestParseStoresDefaultCurrencyInProfile(t *testing.T) {
+	t.Parallel()
+	records := [][]string{
+		{"Date", "Description", "Amount"},
+		{"2024-01-30", "Rent", "-800.00"},
+	}
+	store := profile.NewStore(filepath.Join(t.TempDir(), "profiles.json"))
+	mockLlm := mocks.NewLlm(t)
+	mockLlm.On("FindElements", mock.Anything, mock.Anything, mock.Anything).Return(map[string]int{
+		"date":        0,
+		"description": 1,
+		"amount":      2,
+		"currency":    -1,
+	}, nil).Once()
+
+	_, _, err := parser.NewParser(mockLlm, &parser.Config{Profiles: store, DefaultCurrency: document.EUR}).Parse(context.Background(), records)
+	require.NoError(t, err)
+
+	// The second import has no -currency but the profile remembers it.
+	doc, _, err := parser.NewParser(mockLlm, &parser.Config{Profiles: store}).Parse(context.Background(), records)
+	require.NoError(t, err)
+	require.Equal(t, document.EUR, doc.Transactions[0].Currency)
+	require.Equal(t, document.CurrencySourceProfile, doc.Transactions[0].CurrencySource)
+}
+
+func TestParseReport(t *testing.T) {
+	t.Parallel()
+	records := [][]string{
+		{"Account statement", ""},
+		{"Date", "Description", "Amount", "Currency"},
+		{"2024-01-30", "ICA Maxi", "-45.50", "SEK"},
+		{"", "", "", ""},
+		{"2024-01-31", "Total Wines", "-12.00", "SEK"},
+		{"Date", "Description", "Amount", "Currency"},
+		{"2024-02-01", "Refund", "abc", "SEK"},
+		{"yesterday", "Coffee", "-3.00", "SEK"},
+		{"2024-02-02", "Rent", "-800.00", ""},
+		{"2024-02-03", "Salary", "2000.00", "SEK"},
+		{"Closing balance:", "", "1142.50", "SEK"},
+		{"Printed 2024-02-04", "", "", ""},
+	}
+	mockLlm := mocks.NewLlm(t)
+	mockLlm.On("FindElements", mock.Anything, mock.Anything, mock.Anything).Return(map[string]int{
+		"date":        0,
+		"description": 1,
+		"amount":      2,
+		"currency":    3,
+	}, nil)
+
+	doc, report, err := parser.NewParser(mockLlm, nil).Parse(context.Background(), records)
+	require.NoError(t, err)
+	require.Len(t, doc.Transactions, 3)
+	require.Equal(t, "Total Wines", doc.Transactions[1].Description)
+	require.Equal(t, len(records), report.Rows)
+	require.Equal(t, 3, report.Transactions)
+
+	type issue struct {
+		row   int
+		kind  parser.RowKind
+		field string
+	}
+	var got []issue
+	for _, i := range report.Issues {
+		got = append(got, issue{i.Row, i.Kind, i.Field})
+	}
+	require.Equal(t, []issue{
+		{1, parser.RowPreamble, ""},
+		{2, parser.RowHeader, ""},
+		{4, parser.RowBlank, ""},
+		{6, parser.RowHeader, ""},
+		{7, parser.RowRejected, "amount"},
+		{8, parser.RowRejected, "date"},
+		{9, parser.RowRejected, "currency"},
+		{11, parser.RowSummary, ""},
+		{12, parser.RowFooter, ""},
+	}, got)
+	require.Equal(t, records[6], report.Issues[4].Cells)
+	require.Len(t, report.Rejected(), 3)
+
+	path := filepath.Join(t.TempDir(), "report.csv")
+	require.NoError(t, report.SaveToCSV(path))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	saved, err := reader.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, saved, len(report.Issues)+1)
+	require.Equal(t, []string{"7", "rejected", "amount"}, saved[5][:3])
+}
+
+func TestParseHeaderlessCurrencyColumn(t *testing.T) {
+	t.Parallel()
+	records := [][]string{
+		{"2024-01-30", "ICA Maxi", "-45.50", "USD"},
+		{"2024-01-31", "Salary", "2000.00", "SEK"},
+	}
+	doc, _, err := parser.NewParser(llm.NewHeuristic(nil, 0), nil).Parse(context.Background(), records)
+	require.NoError(t, err)
+	require.Len(t, doc.Transactions, 2)
+	require.Equal(t, document.USD, doc.Transactions[0].Currency)
+	require.Equal(t, document.SEK, doc.Transactions[1].Currency)
+	require.Equal(t, document.CurrencySourceColumn, doc.Transactions[0].CurrencySource)
+}
+
+func TestParseAmountOverflow(t *testing.T) {
+	t.Parallel()
+	records := [][]string{
+		{"Date", "Description", "Debit", "Credit", "Currency"},
+		{"2024-01-30", "Sum too large", "9000000000000000000", "0.5", "SEK"},
+		{"2024-01-30", "Too large in öre", "", "9000000000000000000", "SEK"},
+		{"2024-01-31", "Coffee", "4.50", "", "SEK"},
+	}
+	mockLlm := mocks.NewLlm(t)
+	mockLlm.On("FindElements", mock.Anything, mock.Anything, mock.Anything).Return(map[string]int{
+		"date":        0,
+		"description": 1,
+		"amount":      -1,
+		"debit":       2,
+		"credit":      3,
+		"direction":   -1,
+		"currency":    4,
+	}, nil)
+
+	// Amounts that do not fit reject their row instead of the statement.
+	doc, report, err := parser.NewParser(mockLlm, nil).Parse(context.Background(), records)
+	require.NoError(t, err)
+	require.Len(t, doc.Transactions, 1)
+	require.Equal(t, "-4.50", doc.Transactions[0].Amount.String())
+	rejected := report.Rejected()
+	require.Len(t, rejected, 2)
+	require.Equal(t, "credit", rejected[0].Field)
+	require.Equal(t, "amount", rejected[1].Field)
+}
+
+func TestParseCommaDecimalFooter(t *testing.T) {
+	t.Parallel()
+	// A label-only footer must not be taken for the header when amounts
+	// use a decimal comma.
+	records := [][]string{
+		{"Account", "Nordea Personkonto"},
+		{"Bokföringsdag", "Belopp", "Rubrik", "Valuta"},
+		{"2025-01-02", "-1 234,50", "Hyra", "SEK"},
+		{"2025-01-03", "250,00", "Swish", "SEK"},
+		{"Exported by", "Nordea Bank"},
+	}
+	mockLlm := mocks.NewLlm(t)
+	mockLlm.On("FindElements", mock.Anything, mock.Anything, mock.Anything).Return(map[string]int{
+		"date":        0,
+		"amount":      1,
+		"description": 2,
+		"currency":    3,
+	}, nil)
+
+	doc, report, err := parser.NewParser(mockLlm, nil).Parse(context.Background(), records)
+	require.NoError(t, err)
+	require.Len(t, doc.Transactions, 2)
+	require.Equal(t, "-1234.50", doc.Transactions[0].Amount.String())
+	require.Equal(t, "250.00", doc.Transactions[1].Amount.String())
+
+	var kinds []parser.RowKind
+	for _, i := range report.Issues {
+		kinds = append(kinds, i.Kind)
+	}
+	require.Equal(t, []parser.RowKind{parser.RowPreamble, parser.RowHeader, parser.RowFooter}, kinds)
+}
+
+func TestParseDateLayout(t *testing.T) {
+	t.Parallel()
+	records := [][]string{
+		{"Date", "Description", "Amount", "Currency"},
+		{"03/04/2024", "Coffee", "-3.00", "EUR"},
+		{"04/05/2024", "Rent", "-800.00", "EUR"},
+	}
+	indices := map[string]int{
+		"date":        0,
+		"description": 1,
+		"amount":      2,
+		"currency":    3,
+	}
+
+	mockLlm := mocks.NewLlm(t)
+	mockLlm.On("FindElements", mock.Anything, mock.Anything, mock.Anything).Return(indices, nil)
+
+	// Day and month could be either way round, so the parser does not guess.
+	_, report, err := parser.NewParser(mockLlm, nil).Parse(context.Background(), records)
+	require.ErrorIs(t, err, parser.ErrAmbiguousDates)
+	require.Equal(t, []string{"2/1/2006"}, report.AmbiguousDateLayouts)
+
+	doc, report, err := parser.NewParser(mockLlm, &parser.Config{DateLayout: "02/01/2006"}).Parse(context.Background(), records)
+	require.NoError(t, err)
+	require.Equal(t, "02/01/2006", report.DateLayout)
+	require.Empty(t, report.AmbiguousDateLayouts)
+	require.Equal(t, time.April, doc.Transactions[0].Date.Month())
+	require.Equal(t, time.May, doc.Transactions[1].Date.Month())
+}
+
+func TestParseUnknownCurrency(t *testing.T) {
+	t.Parallel()
+	records := [][]string{
+		{"Date", "Description", "Amount", "Currency"},
+		{"2024-01-30", "Coffee", "-3.00", "sek"},
+		{"2024-01-31", "Rent", "-800.00", "SEKK"},
+		{"2024-02-01", "Lunch", "-9.50", "€"},
+	}
+	mockLlm := mocks.NewLlm(t)
+	mockLlm.On("FindElements", mock.Anything, mock.Anything, mock.Anything).Return(map[string]int{
+		"date":        0,
+		"description": 1,
+		"amount":      2,
+		"currency":    3,
+	}, nil)
+
+	doc, report, err := parser.NewParser(mockLlm, nil).Parse(context.Background(), records)
+	require.NoError(t, err)
+	require.Len(t, doc.Transactions, 2)
+	require.Equal(t, document.SEK, doc.Transactions[0].Currency)
+	require.Equal(t, document.EUR, doc.Transactions[1].Currency)
+	require.Len(t, report.Rejected(), 1)
+	require.Equal(t, 3, report.Rejected()[0].Row)
+	require.Equal(t, "currency", report.Rejected()[0].Field)
+
+	_, _, err = parser.NewParser(mockLlm, &parser.Config{DefaultCurrency: "SEKK"}).Parse(context.Background(), records)
+	require.ErrorIs(t, err, document.ErrUnknownCurrency)
+}
+
+func TestElementNames(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, []string{"amount", "credit", "currency", "date", "debit", "description", "direction"}, parser.ElementNames())
+}
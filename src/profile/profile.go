@@ -0,0 +1,213 @@
+package profile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/document"
+)
+
+var (
+	ErrNotFound  = errors.New("profile not found")
+	ErrAmbiguous = errors.New("fingerprint prefix matches more than one profile")
+)
+
+// Profile is a column mapping remembered for one statement layout. Currency
+// is used for layouts that have no currency column.
+type Profile struct {
+	Fingerprint string            `json:"fingerprint"`
+	Name        string            `json:"name,omitempty"`
+	Header      []string          `json:"header"`
+	Indices     map[string]int    `json:"indices"`
+	Currency    document.Currency `json:"currency,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	LastUsedAt  time.Time         `json:"last_used_at"`
+	UseCount    int               `json:"use_count"`
+}
+
+// Store keeps profiles in a single JSON file.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewStore(path string) *Store {
+	return &Store{
+		path: path,
+	}
+}
+
+// DefaultPath returns profiles.json in the user's config directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user config dir: %w", err)
+	}
+	return filepath.Join(dir, "optimusdime", "profiles.json"), nil
+}
+
+// Fingerprint identifies a header row regardless of case and surrounding
+// whitespace.
+func Fingerprint(header []string) string {
+	normalized := make([]string, len(header))
+	for i, h := range header {
+		normalized[i] = strings.ToLower(strings.TrimSpace(h))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(normalized, "\x1f")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (s *Store) Path() string {
+	return s.path
+}
+
+func (s *Store) List() ([]Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profiles, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Profile, 0, len(profiles))
+	for _, p := range profiles {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].Fingerprint < list[j].Fingerprint
+	})
+	return list, nil
+}
+
+// Get returns the profile with the given fingerprint or unique fingerprint
+// prefix.
+func (s *Store) Get(fingerprint string) (*Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profiles, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	p, err := find(profiles, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *Store) Put(p Profile) error {
+	if p.Fingerprint == "" {
+		return errors.New("profile has no fingerprint")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profiles, err := s.load()
+	if err != nil {
+		return err
+	}
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = time.Now().UTC()
+	}
+	profiles[p.Fingerprint] = p
+	return s.save(profiles)
+}
+
+// MarkUsed records that a stored mapping was reused for an import.
+func (s *Store) MarkUsed(fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profiles, err := s.load()
+	if err != nil {
+		return err
+	}
+	p, ok := profiles[fingerprint]
+	if !ok {
+		return fmt.Errorf("%s: %w", fingerprint, ErrNotFound)
+	}
+	p.LastUsedAt = time.Now().UTC()
+	p.UseCount++
+	profiles[fingerprint] = p
+	return s.save(profiles)
+}
+
+func (s *Store) Delete(fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profiles, err := s.load()
+	if err != nil {
+		return err
+	}
+	p, err := find(profiles, fingerprint)
+	if err != nil {
+		return err
+	}
+	delete(profiles, p.Fingerprint)
+	return s.save(profiles)
+}
+
+func find(profiles map[string]Profile, fingerprint string) (Profile, error) {
+	if p, ok := profiles[fingerprint]; ok {
+		return p, nil
+	}
+	var matches []Profile
+	if fingerprint != "" {
+		for fp, p := range profiles {
+			if strings.HasPrefix(fp, fingerprint) {
+				matches = append(matches, p)
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Profile{}, fmt.Errorf("%s: %w", fingerprint, ErrNotFound)
+	case 1:
+		return matches[0], nil
+	default:
+		return Profile{}, fmt.Errorf("%s: %w", fingerprint, ErrAmbiguous)
+	}
+}
+
+func (s *Store) load() (map[string]Profile, error) {
+	profiles := make(map[string]Profile)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return profiles, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles in %s: %w", s.path, err)
+	}
+	return profiles, nil
+}
+
+func (s *Store) save(profiles map[string]Profile) error {
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode profiles: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create profile dir: %w", err)
+	}
+	// Write to a temporary file first so a crash never leaves a truncated store.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write profiles: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write profiles: %w", err)
+	}
+	return nil
+}
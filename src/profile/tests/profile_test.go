@@ -0,0 +1,93 @@
+package profiletest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lazeratops/optimusdime/src/document"
+	"github.com/lazeratops/optimusdime/src/profile"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+	fp := profile.Fingerprint([]string{"Date", "Amount"})
+	require.Len(t, fp, 16)
+	require.Equal(t, fp, profile.Fingerprint([]string{" date ", "AMOUNT"}))
+	require.NotEqual(t, fp, profile.Fingerprint([]string{"Amount", "Date"}))
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "nested", "profiles.json")
+	store := profile.NewStore(path)
+
+	// A missing file is an empty store.
+	list, err := store.List()
+	require.NoError(t, err)
+	require.Empty(t, list)
+	_, err = store.Get("abc")
+	require.ErrorIs(t, err, profile.ErrNotFound)
+
+	require.Error(t, store.Put(profile.Profile{}))
+	for _, p := range []profile.Profile{
+		{Fingerprint: "aa11", Name: "Nordea", Indices: map[string]int{"date": 0}},
+		{Fingerprint: "aa22", Name: "Revolut", Currency: document.EUR},
+		{Fingerprint: "bb33", Name: "Amex"},
+	} {
+		require.NoError(t, store.Put(p))
+	}
+
+	// The file is written whole, without a temporary file left behind, and
+	// a new store reads it back.
+	_, err = os.Stat(path + ".tmp")
+	require.ErrorIs(t, err, os.ErrNotExist)
+	list, err = profile.NewStore(path).List()
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	require.Equal(t, "Amex", list[0].Name)
+	require.False(t, list[0].CreatedAt.IsZero())
+
+	// Fingerprints may be shortened to a unique prefix.
+	p, err := store.Get("aa2")
+	require.NoError(t, err)
+	require.Equal(t, "Revolut", p.Name)
+	require.Equal(t, document.EUR, p.Currency)
+	_, err = store.Get("aa")
+	require.ErrorIs(t, err, profile.ErrAmbiguous)
+	_, err = store.Get("")
+	require.ErrorIs(t, err, profile.ErrNotFound)
+
+	// MarkUsed only takes a whole fingerprint.
+	require.NoError(t, store.MarkUsed("aa11"))
+	require.NoError(t, store.MarkUsed("aa11"))
+	p, err = store.Get("aa11")
+	require.NoError(t, err)
+	require.Equal(t, 2, p.UseCount)
+	require.False(t, p.LastUsedAt.IsZero())
+	require.ErrorIs(t, store.MarkUsed("aa"), profile.ErrNotFound)
+
+	require.ErrorIs(t, store.Delete("aa"), profile.ErrAmbiguous)
+	require.NoError(t, store.Delete("bb"))
+	_, err = store.Get("bb33")
+	require.ErrorIs(t, err, profile.ErrNotFound)
+	list, err = store.List()
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+}
+
+func TestStoreCorruptFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0o600))
+	store := profile.NewStore(path)
+
+	_, err := store.List()
+	require.Error(t, err)
+	// A corrupt file is not overwritten.
+	require.Error(t, store.Put(profile.Profile{Fingerprint: "aa11"}))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "{not json", string(data))
+}
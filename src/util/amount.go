@@ -0,0 +1,180 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// DetectDecimalSeparator votes across all values for '.' or ',' as the
+// decimal separator. Values such as "1,234" that fit either convention do
+// not vote. When nothing decides, '.' is returned.
+func DetectDecimalSeparator(values []string) rune {
+	votes := map[rune]int{}
+	for _, v := range values {
+		if sep, ok := decimalSeparatorOf(numericPart(v)); ok {
+			votes[sep]++
+		}
+	}
+	if votes[','] > votes['.'] {
+		return ','
+	}
+	return '.'
+}
+
+func decimalSeparatorOf(n string) (rune, bool) {
+	lastDot := strings.LastIndex(n, ".")
+	lastComma := strings.LastIndex(n, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		if lastDot > lastComma {
+			return '.', true
+		}
+		return ',', true
+	case lastDot < 0 && lastComma < 0:
+		return 0, false
+	}
+
+	sep, last := '.', lastDot
+	if lastComma >= 0 {
+		sep, last = ',', lastComma
+	}
+	if strings.Count(n, string(sep)) > 1 {
+		// Repeated separators can only be grouping.
+		if sep == '.' {
+			return ',', true
+		}
+		return '.', true
+	}
+	if len(n)-last-1 == 3 {
+		return 0, false
+	}
+	return sep, true
+}
+
+func numericPart(v string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) || r == '.' || r == ',' {
+			return r
+		}
+		return -1
+	}, v)
+}
+
+// ParseAmount parses amounts such as "1 234,56", "1.234,56", "-kr 45,00",
+// "(12.00)", "45,00-" and "€12.50" using the given decimal separator. It
+// returns the amount as a plain decimal string ("-1234.56") together with
+// any currency symbol or code written next to it.
+func ParseAmount(s string, decimal rune) (string, string, error) {
+	invalid := fmt.Errorf("%q: %w", s, ErrInvalidAmount)
+
+	v := strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(v, "(") && strings.HasSuffix(v, ")") {
+		negative = true
+		v = strings.TrimSpace(v[1 : len(v)-1])
+	}
+
+	first := strings.IndexFunc(v, unicode.IsDigit)
+	if first < 0 {
+		return "", "", invalid
+	}
+	last := strings.LastIndexFunc(v, unicode.IsDigit)
+	if first > 0 && rune(v[first-1]) == decimal {
+		// ".50" style amounts.
+		first--
+	}
+	prefix, core, suffix := v[:first], v[first:last+1], v[last+1:]
+
+	var currency strings.Builder
+	for _, r := range prefix + " " + suffix {
+		switch {
+		case r == '-' || r == '−':
+			if negative {
+				return "", "", invalid
+			}
+			negative = true
+		case unicode.IsLetter(r) || unicode.Is(unicode.Sc, r):
+			currency.WriteRune(r)
+		case r == '+' || r == '.' || unicode.IsSpace(r):
+		default:
+			return "", "", invalid
+		}
+	}
+
+	amount, ok := normalizeNumber(core, decimal)
+	if !ok {
+		return "", "", invalid
+	}
+	if negative && strings.Trim(amount, "0.") != "" {
+		amount = "-" + amount
+	}
+	return amount, currency.String(), nil
+}
+
+// normalizeNumber strips group separators and rewrites the decimal
+// separator as '.'. Groups after the first must have exactly three digits.
+func normalizeNumber(core string, decimal rune) (string, bool) {
+	intPart, fracPart := core, ""
+	if i := strings.IndexRune(core, decimal); i >= 0 {
+		intPart, fracPart = core[:i], core[i+1:]
+	}
+	if !allDigits(fracPart) {
+		return "", false
+	}
+
+	groups := strings.FieldsFunc(intPart, isGroupSeparator)
+	for i, g := range groups {
+		if !allDigits(g) || (i > 0 && len(g) != 3) {
+			return "", false
+		}
+	}
+
+	whole := strings.TrimLeft(strings.Join(groups, ""), "0")
+	if whole == "" {
+		whole = "0"
+	}
+	if fracPart == "" {
+		return whole, true
+	}
+	return whole + "." + fracPart, true
+}
+
+func isGroupSeparator(r rune) bool {
+	switch r {
+	case '.', ',', ' ', '\'', '\u00a0', '\u202f', '\u2009':
+		return true
+	}
+	return false
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+var directions = map[string]int{
+	"d": -1, "dr": -1, "db": -1, "dbit": -1, "debit": -1, "out": -1, "-": -1,
+	"ut": -1, "uttag": -1, "utbetalning": -1,
+	"s": -1, "soll": -1, "belastung": -1, "lastschrift": -1,
+	"af": -1, "débit": -1, "cargo": -1,
+	"c": 1, "cr": 1, "crdt": 1, "credit": 1, "in": 1, "+": 1,
+	"insättning": 1, "inbetalning": 1,
+	"h": 1, "haben": 1, "gutschrift": 1,
+	"bij": 1, "crédit": 1, "abono": 1,
+}
+
+// ParseDirection reads a debit/credit indicator such as "D", "CR", "Out" or
+// "Haben" and returns -1 for money leaving the account and 1 for money
+// coming in.
+func ParseDirection(s string) (int, bool) {
+	sign, ok := directions[strings.ToLower(strings.TrimSpace(s))]
+	return sign, ok
+}
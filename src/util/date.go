@@ -0,0 +1,250 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrNoDateLayout      = errors.New("no date layout fits")
+	ErrInvalidDateFormat = errors.New("invalid date format")
+)
+
+// dateLayouts are tried in order. Where two layouts read the same text
+// differently, e.g. "03/04/2024", the earlier one wins. Day and month
+// layouts use the unpadded form, which also accepts zero-padded values.
+var dateLayouts = []string{
+	"2006-01-02",                // YYYY-MM-DD
+	"2-1-2006",                  // DD-MM-YYYY
+	"1/2/2006",                  // MM/DD/YYYY
+	"2006/1/2",                  // YYYY/MM/DD
+	"2 Jan 2006",                // DD Mon YYYY
+	"2006-01-02T15:04:05Z07:00", // ISO8601
+	"2/1/2006",                  // DD/MM/YYYY
+	"1-2-2006",                  // MM-DD-YYYY
+	"2.1.2006",                  // DD.MM.YYYY
+	"2006.1.2",                  // YYYY.MM.DD
+	"20060102",                  // YYYYMMDD
+	"2-1-06",                    // DD-MM-YY
+	"1/2/06",                    // MM/DD/YY
+	"2/1/06",                    // DD/MM/YY
+	"2.1.06",                    // DD.MM.YY
+	"2. Jan 2006",               // DD. Mon YYYY
+	"2-Jan-2006",                // DD-Mon-YYYY
+	"2-Jan-06",                  // DD-Mon-YY
+	"2 Jan 06",                  // DD Mon YY
+	"Jan 2, 2006",               // Mon DD, YYYY
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2.1.2006 15:04:05",
+	"2.1.2006 15:04",
+	"2/1/2006 15:04:05",
+	"1/2/2006 15:04:05",
+	"2-1-2006 15:04:05",
+}
+
+// monthNames maps English, Swedish and German month names and abbreviations
+// to the abbreviations time.Parse understands.
+var monthNames = map[string]string{
+	"january": "Jan", "januari": "Jan", "januar": "Jan", "jänner": "Jan", "jan": "Jan",
+	"february": "Feb", "februari": "Feb", "februar": "Feb", "feb": "Feb",
+	"march": "Mar", "mars": "Mar", "märz": "Mar", "maerz": "Mar", "mar": "Mar", "mär": "Mar", "mrz": "Mar",
+	"april": "Apr", "apr": "Apr",
+	"may": "May", "maj": "May", "mai": "May",
+	"june": "Jun", "juni": "Jun", "jun": "Jun",
+	"july": "Jul", "juli": "Jul", "jul": "Jul",
+	"august": "Aug", "augusti": "Aug", "aug": "Aug",
+	"september": "Sep", "sept": "Sep", "sep": "Sep",
+	"october": "Oct", "oktober": "Oct", "oct": "Oct", "okt": "Oct",
+	"november": "Nov", "nov": "Nov",
+	"december": "Dec", "dezember": "Dec", "dec": "Dec", "dez": "Dec",
+}
+
+// normalizeMonths rewrites month names as English abbreviations, so that
+// "5 maj 2024" and "5. Mai 2024" parse with the "Jan" layouts.
+func normalizeMonths(s string) string {
+	if strings.IndexFunc(s, unicode.IsLetter) < 0 {
+		return s
+	}
+	var b strings.Builder
+	var word strings.Builder
+	flush := func() {
+		w := word.String()
+		if m, ok := monthNames[strings.ToLower(w)]; ok {
+			w = m
+		}
+		b.WriteString(w)
+		word.Reset()
+	}
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			word.WriteRune(r)
+			continue
+		}
+		flush()
+		b.WriteRune(r)
+	}
+	flush()
+	return b.String()
+}
+
+// ParseDate parses a date in the first layout that fits. Use InferDateLayout
+// to read a whole column consistently.
+func ParseDate(dateStr string) (time.Time, error) {
+	normalized := normalizeMonths(strings.TrimSpace(dateStr))
+
+	var lastErr error
+	for _, format := range dateLayouts {
+		if t, err := time.Parse(format, normalized); err == nil {
+			return t, nil
+		} else {
+			lastErr = err
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("failed to parse date '%s': %w", dateStr, lastErr)
+}
+
+// ParseDateLayout parses a date in the given layout, accepting localized
+// month names.
+func ParseDateLayout(dateStr string, layout string) (time.Time, error) {
+	t, err := time.Parse(layout, normalizeMonths(strings.TrimSpace(dateStr)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse date '%s' as %s: %w", dateStr, layout, err)
+	}
+	return t, nil
+}
+
+// DateInference is the layout chosen for a column of dates.
+type DateInference struct {
+	Layout string
+	// Ambiguous lists other layouts that fit as many values but read at
+	// least one of them as a different date, e.g. "2/1/2006" next to
+	// "1/2/2006" when every day is 12 or less.
+	Ambiguous []string
+	// Misfits counts values that the layout does not fit.
+	Misfits int
+}
+
+// InferDateLayout picks the one layout that fits the most values, so that
+// every row of a statement is read the same way.
+func InferDateLayout(values []string) (DateInference, error) {
+	var nonEmpty []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			nonEmpty = append(nonEmpty, normalizeMonths(v))
+		}
+	}
+
+	if len(nonEmpty) == 0 {
+		return DateInference{Layout: dateLayouts[0]}, nil
+	}
+
+	best, bestFits := -1, 0
+	parsed := make([][]time.Time, len(dateLayouts))
+	for i, layout := range dateLayouts {
+		fits := 0
+		times := make([]time.Time, len(nonEmpty))
+		for j, v := range nonEmpty {
+			t, err := time.Parse(layout, v)
+			if err != nil {
+				continue
+			}
+			times[j] = t
+			fits++
+		}
+		parsed[i] = times
+		if fits > bestFits {
+			best, bestFits = i, fits
+		}
+	}
+	if best < 0 {
+		return DateInference{}, fmt.Errorf("%q: %w", nonEmpty[0], ErrNoDateLayout)
+	}
+
+	inference := DateInference{
+		Layout:  dateLayouts[best],
+		Misfits: len(nonEmpty) - bestFits,
+	}
+	for i, layout := range dateLayouts {
+		if i == best || fitCount(parsed[i]) != bestFits {
+			continue
+		}
+		for j, t := range parsed[i] {
+			if !t.Equal(parsed[best][j]) {
+				inference.Ambiguous = append(inference.Ambiguous, layout)
+				break
+			}
+		}
+	}
+	return inference, nil
+}
+
+func fitCount(times []time.Time) int {
+	n := 0
+	for _, t := range times {
+		if !t.IsZero() {
+			n++
+		}
+	}
+	return n
+}
+
+// dateFormatTokens translate date patterns such as "DD/MM/YYYY" into Go
+// layouts. Longer tokens come first.
+var dateFormatTokens = []struct {
+	token  string
+	layout string
+}{
+	{"YYYY", "2006"},
+	{"MMMM", "Jan"},
+	{"MMM", "Jan"},
+	{"YY", "06"},
+	{"MM", "01"},
+	{"DD", "02"},
+	{"HH", "15"},
+	{"SS", "05"},
+	{"M", "1"},
+	{"D", "2"},
+}
+
+// DateLayout converts a date pattern such as "DD/MM/YYYY", "d.m.yy" or
+// "YYYY-MM-DD HH:mm" to a Go layout. A value that already is a Go layout
+// (it contains 2006 or 06) is returned as is. "mm" after a colon means
+// minutes.
+func DateLayout(format string) (string, error) {
+	if strings.Contains(format, "06") {
+		return format, nil
+	}
+	var b strings.Builder
+	hasYear := false
+	for i := 0; i < len(format); {
+		if strings.HasPrefix(strings.ToUpper(format[i:]), "MM") && i > 0 && format[i-1] == ':' {
+			b.WriteString("04")
+			i += 2
+			continue
+		}
+		matched := false
+		for _, t := range dateFormatTokens {
+			if strings.HasPrefix(strings.ToUpper(format[i:]), t.token) {
+				b.WriteString(t.layout)
+				hasYear = hasYear || strings.HasPrefix(t.token, "Y")
+				i += len(t.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteByte(format[i])
+			i++
+		}
+	}
+	if !hasYear {
+		return "", fmt.Errorf("%q has no year: %w", format, ErrInvalidDateFormat)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,72 @@
+package utiltest
+
+import (
+	"testing"
+
+	"github.com/lazeratops/optimusdime/src/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectDecimalSeparator(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name   string
+		values []string
+		want   rune
+	}{
+		{name: "dot", values: []string{"Amount", "17.76", "-10.00"}, want: '.'},
+		{name: "comma", values: []string{"Belopp", "1 234,56", "-45,00"}, want: ','},
+		{name: "german grouping", values: []string{"1.234,56", "12,00"}, want: ','},
+		{name: "ambiguous defaults to dot", values: []string{"1,234", "5"}, want: '.'},
+		{name: "ambiguous decided by other rows", values: []string{"1.234", "12,5"}, want: ','},
+		{name: "repeated grouping", values: []string{"1,234,567"}, want: '.'},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, string(tc.want), string(util.DetectDecimalSeparator(tc.values)))
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		input        string
+		decimal      rune
+		wantAmount   string
+		wantCurrency string
+		wantErr      error
+	}{
+		{input: "17.76", decimal: '.', wantAmount: "17.76"},
+		{input: "1 234,56", decimal: ',', wantAmount: "1234.56"},
+		{input: "1.234,56", decimal: ',', wantAmount: "1234.56"},
+		{input: "-kr 45,00", decimal: ',', wantAmount: "-45.00", wantCurrency: "kr"},
+		{input: "45,00 kr", decimal: ',', wantAmount: "45.00", wantCurrency: "kr"},
+		{input: "45,00-", decimal: ',', wantAmount: "-45.00"},
+		{input: "(12.00)", decimal: '.', wantAmount: "-12.00"},
+		{input: "€12.50", decimal: '.', wantAmount: "12.50", wantCurrency: "€"},
+		{input: "USD 1,234.50", decimal: '.', wantAmount: "1234.50", wantCurrency: "USD"},
+		{input: "−3,5", decimal: ',', wantAmount: "-3.5"},
+		{input: ".50", decimal: '.', wantAmount: "0.50"},
+		{input: "-0.00", decimal: '.', wantAmount: "0.00"},
+		{input: "1.234,56", decimal: '.', wantErr: util.ErrInvalidAmount},
+		{input: "1,23,4", decimal: '.', wantErr: util.ErrInvalidAmount},
+		{input: "--5", decimal: '.', wantErr: util.ErrInvalidAmount},
+		{input: "abc", decimal: '.', wantErr: util.ErrInvalidAmount},
+		{input: "", decimal: '.', wantErr: util.ErrInvalidAmount},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+			gotAmount, gotCurrency, gotErr := util.ParseAmount(tc.input, tc.decimal)
+			require.ErrorIs(t, gotErr, tc.wantErr)
+			if gotErr == nil {
+				require.Equal(t, tc.wantAmount, gotAmount)
+				require.Equal(t, tc.wantCurrency, gotCurrency)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package utiltest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lazeratops/optimusdime/src/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDate(t *testing.T) {
+	t.Parallel()
+	want := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+	cases := []string{
+		"2024-03-05",
+		"05-03-2024",
+		"03/05/2024",
+		"05.03.2024",
+		"5.3.24",
+		"20240305",
+		"5 Mar 2024",
+		"5 mars 2024",
+		"5. März 2024",
+		"05-Mar-2024",
+		"March 5, 2024",
+	}
+	for _, input := range cases {
+		input := input
+		t.Run(input, func(t *testing.T) {
+			t.Parallel()
+			got, err := util.ParseDate(input)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		})
+	}
+
+	got, err := util.ParseDate("2024-03-05 14:30:00")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, time.March, 5, 14, 30, 0, 0, time.UTC), got)
+
+	_, err = util.ParseDate("yesterday")
+	require.Error(t, err)
+}
+
+func TestInferDateLayout(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name          string
+		values        []string
+		wantLayout    string
+		wantAmbiguous []string
+		wantMisfits   int
+		wantErr       error
+	}{
+		{
+			name:       "iso",
+			values:     []string{"2024-01-30", "2024-02-01", ""},
+			wantLayout: "2006-01-02",
+		},
+		{
+			name:       "day first proven by one row",
+			values:     []string{"03/04/2024", "04/04/2024", "13/04/2024"},
+			wantLayout: "2/1/2006",
+		},
+		{
+			name:       "month first proven by one row",
+			values:     []string{"03/04/2024", "04/13/2024"},
+			wantLayout: "1/2/2006",
+		},
+		{
+			name:          "slashes cannot be proven",
+			values:        []string{"03/04/2024", "04/05/2024"},
+			wantLayout:    "1/2/2006",
+			wantAmbiguous: []string{"2/1/2006"},
+		},
+		{
+			name:       "same date under every layout",
+			values:     []string{"01/01/2024", "2/2/2024"},
+			wantLayout: "1/2/2006",
+		},
+		{
+			name:       "dots with two-digit years",
+			values:     []string{"30.01.24", "1.2.24"},
+			wantLayout: "2.1.06",
+		},
+		{
+			name:       "swedish months",
+			values:     []string{"5 maj 2024", "12 okt 2024"},
+			wantLayout: "2 Jan 2006",
+		},
+		{
+			name:       "timestamps",
+			values:     []string{"30.01.2024 14:30", "31.01.2024 09:05"},
+			wantLayout: "2.1.2006 15:04",
+		},
+		{
+			name:        "misfits",
+			values:      []string{"2024-01-30", "2024-01-31", "Total"},
+			wantLayout:  "2006-01-02",
+			wantMisfits: 1,
+		},
+		{
+			name:    "no dates",
+			values:  []string{"Coffee", "Rent"},
+			wantErr: util.ErrNoDateLayout,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := util.InferDateLayout(tc.values)
+			require.ErrorIs(t, err, tc.wantErr)
+			if err != nil {
+				return
+			}
+			require.Equal(t, tc.wantLayout, got.Layout)
+			require.Equal(t, tc.wantAmbiguous, got.Ambiguous)
+			require.Equal(t, tc.wantMisfits, got.Misfits)
+		})
+	}
+}
+
+func TestDateLayout(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		format  string
+		want    string
+		wantErr error
+	}{
+		{format: "DD/MM/YYYY", want: "02/01/2006"},
+		{format: "mm/dd/yy", want: "01/02/06"},
+		{format: "D.M.YYYY", want: "2.1.2006"},
+		{format: "YYYY-MM-DD HH:mm:ss", want: "2006-01-02 15:04:05"},
+		{format: "DD MMM YYYY", want: "02 Jan 2006"},
+		{format: "02.01.2006", want: "02.01.2006"},
+		{format: "DD/MM", wantErr: util.ErrInvalidDateFormat},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.format, func(t *testing.T) {
+			t.Parallel()
+			got, err := util.DateLayout(tc.format)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}